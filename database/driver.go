@@ -5,6 +5,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sync"
@@ -81,6 +82,67 @@ type Driver interface {
 	Drop() error
 }
 
+// LockerContext is an optional interface a Driver can implement, if acquiring
+// its lock can be aborted. Migrate calls LockContext in place of Lock then and
+// cancels ctx once the lock timeout is exceeded.
+type LockerContext interface {
+	// LockContext is Lock, returning early with an error if ctx is done.
+	LockContext(ctx context.Context) error
+}
+
+// RunnerContext is an optional interface a Driver can implement, if a running
+// migration can be aborted. Migrate calls RunContext in place of Run then and
+// cancels ctx once the context of the run is done.
+type RunnerContext interface {
+	// RunContext is Run, returning early with an error if ctx is done.
+	RunContext(ctx context.Context, migration io.Reader) error
+}
+
+// TransientErrorer is an optional interface a Driver can implement, to tell
+// which of its errors are transient and worth retrying, see
+// migrate.Migrate.SetRetry.
+type TransientErrorer interface {
+	// IsTransient reports whether err is likely to go away on retry, e.g.
+	// because the server is starting up.
+	IsTransient(err error) bool
+}
+
+// TryLocker is an optional interface a Driver can implement, if it can try
+// to acquire the lock without waiting, see Migrate.TryLock.
+type TryLocker interface {
+	// TryLock is Lock, returning false instead of waiting if the lock is
+	// held by someone else.
+	TryLock() (bool, error)
+}
+
+// Checksummer is an optional interface a Driver can implement, if it can
+// persist a checksum per applied migration. Migrate records the checksum of
+// every up migration it runs then, and Migrate.Verify compares them with the
+// checksums of the migrations in the source.
+type Checksummer interface {
+	// SetChecksum saves the checksum of the up migration of version.
+	SetChecksum(version uint, checksum string) error
+
+	// Checksums returns the saved checksums by version.
+	Checksums() (map[uint]string, error)
+}
+
+// Transactioner is an optional interface a Driver can implement, if it can
+// run several migrations in one transaction, e.g. because it supports
+// transactional DDL. Migrate.WholeRunInTransaction requires it.
+// Between Begin and Commit or Rollback, Run and SetVersion must take part
+// in the transaction.
+type Transactioner interface {
+	// Begin starts the transaction.
+	Begin() error
+
+	// Commit commits the transaction started by Begin.
+	Commit() error
+
+	// Rollback rolls back the transaction started by Begin.
+	Rollback() error
+}
+
 // Open returns a new driver instance.
 func Open(url string) (Driver, error) {
 	scheme, err := iurl.SchemeFromURL(url)
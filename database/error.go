@@ -25,3 +25,9 @@ func (e Error) Error() string {
 	}
 	return fmt.Sprintf("%v in line %v: %s (details: %v)", e.Err, e.Line, e.Query, e.OrigErr)
 }
+
+// Unwrap returns OrigErr, so that errors.Is and errors.As inspect the
+// underlying error.
+func (e Error) Unwrap() error {
+	return e.OrigErr
+}
@@ -13,6 +13,7 @@ import (
 	"io"
 	"io/ioutil"
 	nurl "net/url"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -35,12 +36,27 @@ var (
 	ErrNoDatabaseName   = fmt.Errorf("no database name")
 	ErrAppendPEM        = fmt.Errorf("failed to append PEM")
 	ErrTLSCertKeyConfig = fmt.Errorf("To use TLS client authentication, both x-tls-cert and x-tls-key must not be empty")
+	ErrNoCharset        = fmt.Errorf("x-collation requires x-charset")
 )
 
+// charsetRegex matches the names of character sets and collations, which
+// can't be bound as arguments of SET NAMES.
+var charsetRegex = regexp.MustCompile(`^\w+$`)
+
 type Config struct {
 	MigrationsTable string
 	DatabaseName    string
 	NoLock          bool
+	// DisableForeignKeyChecks runs every migration with FOREIGN_KEY_CHECKS
+	// disabled on the connection, restoring the previous setting afterwards,
+	// e.g. to drop and recreate referenced tables.
+	DisableForeignKeyChecks bool
+	// Charset and Collation are set with SET NAMES on the connection running
+	// the migrations, e.g. utf8mb4 and utf8mb4_unicode_ci, which string
+	// literals of migrations are interpreted in. The connection's settings
+	// are kept if Charset is empty. Collation requires Charset.
+	Charset   string
+	Collation string
 }
 
 type Mysql struct {
@@ -69,6 +85,14 @@ func WithConnection(ctx context.Context, conn *sql.Conn, config *Config) (*Mysql
 		config: config,
 	}
 
+	if query, err := charsetQuery(config.Charset, config.Collation); err != nil {
+		return nil, err
+	} else if query != "" {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+	}
+
 	if config.DatabaseName == "" {
 		query := `SELECT DATABASE()`
 		var databaseName sql.NullString
@@ -94,6 +118,28 @@ func WithConnection(ctx context.Context, conn *sql.Conn, config *Config) (*Mysql
 	return mx, nil
 }
 
+// charsetQuery returns the SET NAMES statement of charset and collation,
+// empty if neither is set.
+func charsetQuery(charset, collation string) (string, error) {
+	if charset == "" {
+		if collation != "" {
+			return "", ErrNoCharset
+		}
+		return "", nil
+	}
+	if !charsetRegex.MatchString(charset) {
+		return "", fmt.Errorf("invalid x-charset %q", charset)
+	}
+	query := "SET NAMES " + charset
+	if collation != "" {
+		if !charsetRegex.MatchString(collation) {
+			return "", fmt.Errorf("invalid x-collation %q", collation)
+		}
+		query += " COLLATE " + collation
+	}
+	return query, nil
+}
+
 // instance must have `multiStatements` set to true
 func WithInstance(instance *sql.DB, config *Config) (database.Driver, error) {
 	ctx := context.Background()
@@ -241,15 +287,26 @@ func (m *Mysql) Open(url string) (database.Driver, error) {
 		}
 	}
 
+	disableForeignKeyChecksParam, disableForeignKeyChecks := customParams["x-disable-foreign-key-checks"], false
+	if disableForeignKeyChecksParam != "" {
+		disableForeignKeyChecks, err = strconv.ParseBool(disableForeignKeyChecksParam)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse x-disable-foreign-key-checks as bool: %w", err)
+		}
+	}
+
 	db, err := sql.Open("mysql", config.FormatDSN())
 	if err != nil {
 		return nil, err
 	}
 
 	mx, err := WithInstance(db, &Config{
-		DatabaseName:    config.DBName,
-		MigrationsTable: customParams["x-migrations-table"],
-		NoLock:          noLock,
+		DatabaseName:            config.DBName,
+		MigrationsTable:         customParams["x-migrations-table"],
+		NoLock:                  noLock,
+		DisableForeignKeyChecks: disableForeignKeyChecks,
+		Charset:                 customParams["x-charset"],
+		Collation:               customParams["x-collation"],
 	})
 	if err != nil {
 		return nil, err
@@ -321,12 +378,32 @@ func (m *Mysql) Unlock() error {
 	})
 }
 
-func (m *Mysql) Run(migration io.Reader) error {
+// Run sends the whole migration in one round trip, which requires
+// multiStatements=true on the connection.
+func (m *Mysql) Run(migration io.Reader) (err error) {
 	migr, err := ioutil.ReadAll(migration)
 	if err != nil {
 		return err
 	}
 
+	if m.config.DisableForeignKeyChecks {
+		query := "SET @migrate_foreign_key_checks = @@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS = 0"
+		if _, err := m.conn.ExecContext(context.Background(), query); err != nil {
+			return database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		defer func() {
+			query := "SET FOREIGN_KEY_CHECKS = @migrate_foreign_key_checks"
+			if _, errRestore := m.conn.ExecContext(context.Background(), query); errRestore != nil {
+				e := database.Error{OrigErr: errRestore, Query: []byte(query)}
+				if err == nil {
+					err = e
+				} else {
+					err = multierror.Append(err, e)
+				}
+			}
+		}()
+	}
+
 	query := string(migr[:])
 	if _, err := m.conn.ExecContext(context.Background(), query); err != nil {
 		return database.Error{OrigErr: err, Err: "migration failed", Query: migr}
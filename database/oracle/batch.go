@@ -0,0 +1,202 @@
+package oracle
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/godror/godror"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+// singleRowInsertRegex matches INSERT INTO table [(columns)] VALUES (values),
+// capturing the statement up to the values and the values.
+var singleRowInsertRegex = regexp.MustCompile(`(?is)^(insert\s+into\s+[^\s(]+\s*(?:\([^)]*\)\s*)?values\s*)\((.*)\)$`)
+
+// numberLiteralRegex matches the plain decimal number literals bound as
+// godror.Number.
+var numberLiteralRegex = regexp.MustCompile(`^-?(\d+(\.\d*)?|\.\d+)`)
+
+// batchSavepoint is the savepoint restoring the transaction of Begin or
+// TxModePerFile if a batch fails.
+const batchSavepoint = "MIGRATE_BATCH"
+
+type literalKind int
+
+const (
+	nullLiteral literalKind = iota
+	numberLiteral
+	stringLiteral
+)
+
+// literal is a value of a single-row INSERT.
+type literal struct {
+	kind  literalKind
+	value string
+}
+
+// parseInsert splits a single-row INSERT whose values are all number or
+// string literals or NULL into its statement up to the values, with the
+// whitespace normalized, and the values. ok is false for other statements,
+// e.g. ones using functions, sequences or bind placeholders.
+func parseInsert(query string) (head string, values []literal, ok bool) {
+	match := singleRowInsertRegex.FindStringSubmatch(query)
+	if match == nil {
+		return "", nil, false
+	}
+	head = strings.Join(strings.Fields(match[1]), " ")
+
+	rest := match[2]
+	for {
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		var value literal
+		switch {
+		case strings.HasPrefix(rest, "'"):
+			var b strings.Builder
+			i := 1
+			for {
+				j := strings.IndexByte(rest[i:], '\'')
+				if j < 0 {
+					return "", nil, false
+				}
+				b.WriteString(rest[i : i+j])
+				i += j + 1
+				if !strings.HasPrefix(rest[i:], "'") {
+					break
+				}
+				// '' is an escaped quote
+				b.WriteByte('\'')
+				i++
+			}
+			value = literal{kind: stringLiteral, value: b.String()}
+			rest = rest[i:]
+		case numberLiteralRegex.MatchString(rest):
+			n := numberLiteralRegex.FindString(rest)
+			value = literal{kind: numberLiteral, value: n}
+			rest = rest[len(n):]
+		case len(rest) >= 4 && strings.EqualFold(rest[:4], "NULL"):
+			value = literal{kind: nullLiteral}
+			rest = rest[4:]
+		default:
+			return "", nil, false
+		}
+		values = append(values, value)
+
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		if rest == "" {
+			return head, values, true
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return "", nil, false
+		}
+		rest = rest[1:]
+	}
+}
+
+// insertBatch returns the leading queries which can be sent as a single
+// statement with array binds, at most BatchArraySize: consecutive single-row
+// INSERTs into the same columns whose values are literals of the same kind
+// per column. The statement binds the values as :1, :2, ... with a slice
+// per column. n is 0 unless at least two queries are batched.
+func (ora *Oracle) insertBatch(queries []string) (query string, args []interface{}, n int) {
+	var head string
+	var kinds []literalKind
+	var rows [][]literal
+	for n < len(queries) && n < ora.config.BatchArraySize && !ora.usesLobs(queries[n]) {
+		h, values, ok := parseInsert(queries[n])
+		if !ok || (n > 0 && (h != head || len(values) != len(kinds))) {
+			break
+		}
+		if n == 0 {
+			head, kinds = h, make([]literalKind, len(values))
+		}
+		if !mergeKinds(kinds, values) {
+			break
+		}
+		rows = append(rows, values)
+		n++
+	}
+	if n < 2 {
+		return "", nil, 0
+	}
+
+	placeholders := make([]string, len(kinds))
+	for c, kind := range kinds {
+		placeholders[c] = fmt.Sprintf(":%d", c+1)
+		// NULL is bound as an empty string or an empty number
+		if kind == stringLiteral {
+			column := make([]string, n)
+			for i, row := range rows {
+				column[i] = row[c].value
+			}
+			args = append(args, column)
+		} else {
+			column := make([]godror.Number, n)
+			for i, row := range rows {
+				column[i] = godror.Number(row[c].value)
+			}
+			args = append(args, column)
+		}
+	}
+	return head + " (" + strings.Join(placeholders, ", ") + ")", args, n
+}
+
+// mergeKinds sets the kinds of the columns still NULL to the kinds of values
+// and reports whether values matches the kinds of the other columns. kinds
+// is only changed if it does.
+func mergeKinds(kinds []literalKind, values []literal) bool {
+	for c, value := range values {
+		if value.kind != nullLiteral && kinds[c] != nullLiteral && kinds[c] != value.kind {
+			return false
+		}
+	}
+	for c, value := range values {
+		if value.kind != nullLiteral {
+			kinds[c] = value.kind
+		}
+	}
+	return true
+}
+
+// execBatch executes a batch of insertBatch. Array DML keeps the rows
+// inserted before a failing one, so the batch runs in a transaction of its
+// own, or behind a savepoint within the transaction of Begin or TxModePerFile,
+// which is rolled back if it fails. ok is false then, with err only set if
+// the batch couldn't be rolled back.
+func (ora *Oracle) execBatch(query string, args []interface{}) (result sql.Result, ok bool, err error) {
+	ctx := ora.runContext()
+	if ora.tx != nil {
+		if _, err := ora.tx.ExecContext(ctx, "SAVEPOINT "+batchSavepoint); err != nil {
+			return nil, false, &database.Error{OrigErr: err, Err: "batch savepoint failed"}
+		}
+		result, errExec := ora.execArgsWithRetry(query, args...)
+		if errExec == nil {
+			return result, true, nil
+		}
+		if _, err := ora.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+batchSavepoint); err != nil {
+			return nil, false, &database.Error{OrigErr: multierror.Append(errExec, err), Err: "batch rollback failed"}
+		}
+		return nil, false, nil
+	}
+
+	tx, err := ora.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+	ora.tx = tx
+	result, errExec := ora.execArgsWithRetry(query, args...)
+	ora.tx = nil
+	if errExec != nil {
+		if err := tx.Rollback(); err != nil {
+			return nil, false, &database.Error{OrigErr: multierror.Append(errExec, err), Err: "batch rollback failed"}
+		}
+		return nil, false, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+	return result, true, nil
+}
@@ -0,0 +1,6 @@
+CREATE TABLE USERS_PLSQL (
+  USER_ID integer unique,
+  NAME    varchar(40),
+  EMAIL   varchar(40)
+);
+/
@@ -0,0 +1,4 @@
+DELETE FROM USERS_PLSQL;
+/
+DROP PROCEDURE ADD_USER_PLSQL;
+/
@@ -0,0 +1,17 @@
+-- the procedure body contains several semicolons, only the "/" ends it
+CREATE OR REPLACE PROCEDURE ADD_USER_PLSQL (
+  p_user_id IN integer,
+  p_name    IN varchar2
+) AS
+  v_email varchar2(40);
+BEGIN
+  v_email := LOWER(p_name) || '@example.com';
+  INSERT INTO USERS_PLSQL (USER_ID, NAME, EMAIL) VALUES (p_user_id, p_name, v_email);
+END ADD_USER_PLSQL;
+/
+
+BEGIN
+  ADD_USER_PLSQL(1, 'Alice');
+  ADD_USER_PLSQL(2, 'Bob');
+END;
+/
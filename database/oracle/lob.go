@@ -0,0 +1,85 @@
+package oracle
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/godror/godror"
+	"github.com/hashicorp/go-multierror"
+)
+
+// lobDirectiveRegex matches the lines "--migrate:lob :<name> FROM <file>",
+// optionally followed by "AS BLOB" or "AS CLOB".
+var lobDirectiveRegex = regexp.MustCompile(`(?im)^--migrate:lob[ \t]+:(\w+)[ \t]+from[ \t]+(\S+)(?:[ \t]+as[ \t]+(blob|clob))?[ \t]*$`)
+
+// lobDirective binds the contents of file to the placeholder :name.
+type lobDirective struct {
+	name   string
+	file   string
+	isClob bool
+}
+
+// lobDirectives returns the --migrate:lob lines of migration in order.
+func lobDirectives(migration []byte) []lobDirective {
+	var directives []lobDirective
+	for _, match := range lobDirectiveRegex.FindAllSubmatch(migration, -1) {
+		directives = append(directives, lobDirective{
+			name:   string(match[1]),
+			file:   string(match[2]),
+			isClob: strings.EqualFold(string(match[3]), "clob"),
+		})
+	}
+	return directives
+}
+
+// placeholderRegex returns the regex matching the placeholder :name.
+func (d lobDirective) placeholderRegex() *regexp.Regexp {
+	return regexp.MustCompile(`(?i):` + regexp.QuoteMeta(d.name) + `\b`)
+}
+
+// usesLobs reports whether query references the placeholder of a
+// --migrate:lob line of the running migration.
+func (ora *Oracle) usesLobs(query string) bool {
+	for _, d := range ora.lobs {
+		if d.placeholderRegex().MatchString(query) {
+			return true
+		}
+	}
+	return false
+}
+
+// lobArgs opens the files bound to the placeholders query references,
+// relative to LobDir. godror streams the files into temporary LOBs while
+// the statement is executed. The returned function closes the files.
+func (ora *Oracle) lobArgs(query string) (args []interface{}, closeFiles func() error, err error) {
+	var files []*os.File
+	closeFiles = func() error {
+		var result error
+		for _, f := range files {
+			if err := f.Close(); err != nil {
+				result = multierror.Append(result, err)
+			}
+		}
+		return result
+	}
+
+	for _, d := range ora.lobs {
+		if !d.placeholderRegex().MatchString(query) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(ora.config.LobDir, filepath.FromSlash(d.file)))
+		if err != nil {
+			if errClose := closeFiles(); errClose != nil {
+				err = multierror.Append(err, errClose)
+			}
+			return nil, nil, fmt.Errorf("unable to open the LOB of :%s: %w", d.name, err)
+		}
+		files = append(files, f)
+		args = append(args, sql.Named(d.name, godror.Lob{Reader: f, IsClob: d.isClob}))
+	}
+	return args, closeFiles, nil
+}
@@ -0,0 +1,159 @@
+package oracle
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// statementReader reads the statements of a multi-statement migration one
+// at a time, so the migration isn't held in memory as a whole. Statements
+// end on lines for which isTerminator returns true. Empty and comment lines
+// are ignored. The trailing ";" is removed from every statement isPLSQL
+// returns false for.
+type statementReader struct {
+	scanner      *bufio.Scanner
+	isTerminator func(line string) bool
+	isPLSQL      func(s string) bool
+	// comment, if set, is called with every comment line. An error stops
+	// reading and is returned by Err.
+	comment func(line string) error
+
+	buf  bytes.Buffer
+	done bool
+	err  error
+}
+
+// newLineScanner returns a scanner of the lines of rd, which may be up to
+// maxSize bytes long rather than bufio.MaxScanTokenSize.
+func newLineScanner(rd io.Reader, maxSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(rd)
+	initial := bufio.MaxScanTokenSize
+	if maxSize < initial {
+		initial = maxSize
+	}
+	scanner.Buffer(make([]byte, 0, initial), maxSize)
+	return scanner
+}
+
+// newMultiStatementReader returns a reader splitting rd on lines equal to
+// separator. Lines may be up to maxSize bytes long.
+func newMultiStatementReader(rd io.Reader, separator string, maxSize int) *statementReader {
+	isTerminator := func(line string) bool {
+		return line == separator
+	}
+	return &statementReader{scanner: newLineScanner(rd, maxSize), isTerminator: isTerminator, isPLSQL: isPLSQLTail}
+}
+
+// newPLSQLStatementReader returns a reader splitting rd on lines holding a
+// single "/". Lines may be up to maxSize bytes long.
+func newPLSQLStatementReader(rd io.Reader, maxSize int) *statementReader {
+	isTerminator := func(line string) bool {
+		return strings.TrimSpace(line) == "/"
+	}
+	isPLSQL := func(s string) bool {
+		return plsqlBlockRegex.MatchString(s) || isPLSQLTail(s)
+	}
+	return &statementReader{scanner: newLineScanner(rd, maxSize), isTerminator: isTerminator, isPLSQL: isPLSQL}
+}
+
+// Next returns the next statement, skipping empty ones. ok is false once
+// all statements were read or reading failed, see Err.
+func (r *statementReader) Next() (query string, ok bool) {
+	for !r.done {
+		if !r.scanner.Scan() {
+			// the final statement lacks a terminator
+			r.done = true
+		} else if line := r.scanner.Text(); !r.isTerminator(line) {
+			if strings.HasPrefix(line, "--") && r.comment != nil {
+				if r.err = r.comment(line); r.err != nil {
+					r.done = true
+					return "", false
+				}
+			}
+			if line != "" && !strings.HasPrefix(line, "--") {
+				r.buf.WriteString(line + "\n")
+			}
+			continue
+		}
+
+		query = r.trim(r.buf.String())
+		r.buf.Reset()
+		if query != "" {
+			return query, true
+		}
+	}
+	return "", false
+}
+
+// Err returns the error which stopped Next.
+func (r *statementReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.scanner.Err()
+}
+
+// trim removes the surrounding whitespace of a statement and its trailing
+// ";" unless it's PL/SQL.
+func (r *statementReader) trim(query string) string {
+	query = strings.TrimSpace(query)
+	if !r.isPLSQL(query) {
+		// remove the ";" from the tail if it's not PL/SQL stmt
+		query = strings.TrimSuffix(query, ";")
+	}
+	return query
+}
+
+// statementStream holds the statements of a migration read ahead of the
+// running one, e.g. to batch them.
+type statementStream struct {
+	next  func() (query string, ok bool, err error)
+	ahead []string
+	// offset is the position of the first statement of ahead within the
+	// migration.
+	offset int
+}
+
+func newStatementStream(next func() (query string, ok bool, err error)) *statementStream {
+	return &statementStream{next: next}
+}
+
+// sliceStatements returns a next function of a statementStream returning
+// queries.
+func sliceStatements(queries []string) func() (string, bool, error) {
+	return func() (string, bool, error) {
+		if len(queries) == 0 {
+			return "", false, nil
+		}
+		query := queries[0]
+		queries = queries[1:]
+		return query, true, nil
+	}
+}
+
+// peek returns the next n statements without consuming them, fewer once
+// the migration ends.
+func (s *statementStream) peek(n int) ([]string, error) {
+	for len(s.ahead) < n {
+		query, ok, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		s.ahead = append(s.ahead, query)
+	}
+	if n > len(s.ahead) {
+		n = len(s.ahead)
+	}
+	return s.ahead[:n], nil
+}
+
+// advance consumes the next n statements returned by peek.
+func (s *statementStream) advance(n int) {
+	s.ahead = append(s.ahead[:0], s.ahead[n:]...)
+	s.offset += n
+}
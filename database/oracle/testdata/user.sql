@@ -5,3 +5,14 @@ grant create session to orcl;
 grant connect, resource to orcl;
 grant all privileges to orcl;
 
+
+create user migrations identified by migrations quota unlimited on users;
+
+create user tenant1 identified by tenant1 quota unlimited on users;
+
+create role migrate_ddl;
+grant create table, create procedure to migrate_ddl;
+grant migrate_ddl to orcl;
+
+create user preflight identified by preflight;
+grant create session to preflight;
@@ -0,0 +1,98 @@
+package oracle
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// walletDirectoryRegex matches the DIRECTORY of the WALLET_LOCATION in the
+// sqlnet.ora of an Autonomous Database wallet, which points to
+// "?/network/admin", i.e. the Oracle home, as downloaded.
+var walletDirectoryRegex = regexp.MustCompile(`(?i)(DIRECTORY[ \t]*=[ \t]*)"[^"]*"`)
+
+// extractWallet extracts the zipped wallet of an Autonomous Database into a
+// new temporary directory, which the caller removes when done. Its sqlnet.ora
+// is changed to look up the wallet in that directory, so the directory can be
+// used as TNS_ADMIN as is.
+func extractWallet(path string) (dir string, err error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if errClose := r.Close(); errClose != nil {
+			err = multierror.Append(err, errClose)
+		}
+	}()
+
+	dir, err = os.MkdirTemp("", "migrate-adb-wallet-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if errRemove := os.RemoveAll(dir); errRemove != nil {
+				err = multierror.Append(err, errRemove)
+			}
+			dir = ""
+		}
+	}()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		// the files of a wallet are flat, don't follow paths out of dir
+		if err := extractFile(f, filepath.Join(dir, filepath.Base(f.Name))); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "tnsnames.ora")); err != nil {
+		return "", fmt.Errorf("no tnsnames.ora in wallet %v: %w", path, err)
+	}
+	sqlnet := filepath.Join(dir, "sqlnet.ora")
+	b, err := os.ReadFile(sqlnet)
+	if errors.Is(err, os.ErrNotExist) {
+		return dir, nil
+	} else if err != nil {
+		return "", err
+	}
+	b = walletDirectoryRegex.ReplaceAll(b, []byte(`${1}"`+strings.ReplaceAll(dir, "$", "$$")+`"`))
+	if err := os.WriteFile(sqlnet, b, 0600); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// extractFile writes the contents of f to path, readable by the owner only
+// as the wallet holds credentials.
+func extractFile(f *zip.File, path string) (err error) {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := r.Close(); errClose != nil {
+			err = multierror.Append(err, errClose)
+		}
+	}()
+
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,149 @@
+//go:build go1.9
+// +build go1.9
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/hashicorp/go-multierror"
+	"github.com/lib/pq"
+)
+
+// copyDirectiveRegex matches the lines "--migrate:copy <table> FROM <file>".
+var copyDirectiveRegex = regexp.MustCompile(`(?im)^[ \t]*--migrate:copy[ \t]+(\S+)[ \t]+from[ \t]+(\S+)[ \t]*$`)
+
+// copyDirective is a --migrate:copy line at statement[start:end].
+type copyDirective struct {
+	start, end int
+	table      string
+	file       string
+}
+
+// copyDirectives returns the --migrate:copy lines of statement in order.
+func copyDirectives(statement []byte) []copyDirective {
+	var directives []copyDirective
+	for _, loc := range copyDirectiveRegex.FindAllSubmatchIndex(statement, -1) {
+		directives = append(directives, copyDirective{
+			start: loc[0],
+			end:   loc[1],
+			table: string(statement[loc[2]:loc[3]]),
+			file:  string(statement[loc[4]:loc[5]]),
+		})
+	}
+	return directives
+}
+
+// runCopyStatement runs statement, loading the data files of its
+// --migrate:copy lines with COPY where they appear. The SQL around the
+// directives is run in separate statements.
+func (p *Postgres) runCopyStatement(ctx context.Context, statement []byte) error {
+	last := 0
+	for _, directive := range copyDirectives(statement) {
+		if err := p.execStatement(ctx, statement[last:directive.start]); err != nil {
+			return err
+		}
+		if err := p.copyFrom(ctx, directive.table, directive.file); err != nil {
+			return database.Error{OrigErr: err, Err: "copy failed", Query: statement[directive.start:directive.end]}
+		}
+		last = directive.end
+	}
+	return p.execStatement(ctx, statement[last:])
+}
+
+// copyFrom loads the CSV file, relative to CopyDir, into table with COPY.
+// The first record of the file names the columns, empty fields are NULL.
+// The rows are loaded in the transaction started by Begin, if any, or in
+// a transaction of their own.
+func (p *Postgres) copyFrom(ctx context.Context, table, file string) (err error) {
+	f, err := os.Open(filepath.Join(p.config.CopyDir, filepath.FromSlash(file)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := f.Close(); errClose != nil {
+			err = multierror.Append(err, errClose)
+		}
+	}()
+
+	r := csv.NewReader(f)
+	columns, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read the columns of %v: %w", file, err)
+	}
+
+	ctx, cancel := p.statementContext(ctx)
+	defer cancel()
+
+	tx := p.tx
+	if tx == nil {
+		if tx, err = p.conn.BeginTx(ctx, nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				if errRollback := tx.Rollback(); errRollback != nil {
+					err = multierror.Append(err, errRollback)
+				}
+				return
+			}
+			err = tx.Commit()
+		}()
+	}
+
+	if settings := p.timeoutSettings(); settings != "" {
+		if _, err := tx.ExecContext(ctx, settings); err != nil {
+			return err
+		}
+	}
+
+	query := pq.CopyIn(table, columns...)
+	if i := strings.Index(table, "."); i >= 0 {
+		query = pq.CopyInSchema(table[:i], table[i+1:], columns...)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	if err := copyRows(ctx, stmt, r); err != nil {
+		if errClose := stmt.Close(); errClose != nil {
+			err = multierror.Append(err, errClose)
+		}
+		return fmt.Errorf("unable to copy %v: %w", file, err)
+	}
+	return stmt.Close()
+}
+
+// copyRows sends the records of r to the COPY statement stmt.
+func copyRows(ctx context.Context, stmt *sql.Stmt, r *csv.Reader) error {
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		values := make([]interface{}, len(record))
+		for i, field := range record {
+			if field != "" {
+				values[i] = field
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return err
+		}
+	}
+	// flush the buffered rows
+	_, err := stmt.ExecContext(ctx)
+	return err
+}
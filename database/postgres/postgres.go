@@ -42,10 +42,16 @@ var (
 	ErrNoDatabaseName = fmt.Errorf("no database name")
 	ErrNoSchema       = fmt.Errorf("no schema")
 	ErrDatabaseDirty  = fmt.Errorf("database is dirty")
+	ErrTxStarted      = fmt.Errorf("transaction already started")
+	ErrNoTx           = fmt.Errorf("no transaction started")
 )
 
 type Config struct {
-	MigrationsTable       string
+	MigrationsTable string
+	// MigrationsTableVars holds the values of the ${NAME} tokens of
+	// MigrationsTable, e.g. {"ENV": "staging"} for separate migrations
+	// tables per environment. They are expanded once by WithConnection.
+	MigrationsTableVars   map[string]string
 	MigrationsTableQuoted bool
 	MultiStatementEnabled bool
 	DatabaseName          string
@@ -53,7 +59,16 @@ type Config struct {
 	migrationsSchemaName  string
 	migrationsTableName   string
 	StatementTimeout      time.Duration
+	// LockTimeout bounds the time every statement of a migration waits for
+	// a lock, e.g. behind a long-running query holding the table. Like a
+	// set StatementTimeout, it's applied with SET LOCAL on the server, so
+	// the statements of a migration run in a transaction block then.
+	LockTimeout           time.Duration
 	MultiStatementMaxSize int
+	// CopyDir enables the "--migrate:copy <table> FROM <file>" lines of
+	// migrations, loading the CSV file relative to CopyDir into table with
+	// COPY. Such lines are plain comments if CopyDir is empty.
+	CopyDir string
 }
 
 type Postgres struct {
@@ -62,10 +77,18 @@ type Postgres struct {
 	db       *sql.DB
 	isLocked atomic.Bool
 
+	// tx is the transaction started by Begin, Run and SetVersion use it
+	tx *sql.Tx
+
 	// Open and WithInstance need to guarantee that config is never nil
 	config *Config
 }
 
+// execer is implemented by *sql.Conn and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
 func WithConnection(ctx context.Context, conn *sql.Conn, config *Config) (*Postgres, error) {
 	if config == nil {
 		return nil, ErrNilConfig
@@ -103,6 +126,12 @@ func WithConnection(ctx context.Context, conn *sql.Conn, config *Config) (*Postg
 		config.SchemaName = schemaName
 	}
 
+	migrationsTable, err := database.ExpandTableName(config.MigrationsTable, config.MigrationsTableVars)
+	if err != nil {
+		return nil, err
+	}
+	config.MigrationsTable = migrationsTable
+
 	if len(config.MigrationsTable) == 0 {
 		config.MigrationsTable = DefaultMigrationsTable
 	}
@@ -175,6 +204,11 @@ func (p *Postgres) Open(url string) (database.Driver, error) {
 		return nil, fmt.Errorf("x-migrations-table must be quoted (for instance '\"migrate\".\"schema_migrations\"') when x-migrations-table-quoted is enabled, current value is: %s", migrationsTable)
 	}
 
+	migrationsTableVars, err := database.ParseTableNameVars(purl.Query().Get("x-migrations-table-vars"))
+	if err != nil {
+		return nil, err
+	}
+
 	statementTimeoutString := purl.Query().Get("x-statement-timeout")
 	statementTimeout := 0
 	if statementTimeoutString != "" {
@@ -184,6 +218,14 @@ func (p *Postgres) Open(url string) (database.Driver, error) {
 		}
 	}
 
+	lockTimeout := 0
+	if s := purl.Query().Get("x-lock-timeout"); s != "" {
+		lockTimeout, err = strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse option x-lock-timeout: %w", err)
+		}
+	}
+
 	multiStatementMaxSize := DefaultMultiStatementMaxSize
 	if s := purl.Query().Get("x-multi-statement-max-size"); len(s) > 0 {
 		multiStatementMaxSize, err = strconv.Atoi(s)
@@ -203,13 +245,18 @@ func (p *Postgres) Open(url string) (database.Driver, error) {
 		}
 	}
 
+	copyDir := purl.Query().Get("x-copy-dir")
+
 	px, err := WithInstance(db, &Config{
 		DatabaseName:          purl.Path,
 		MigrationsTable:       migrationsTable,
+		MigrationsTableVars:   migrationsTableVars,
 		MigrationsTableQuoted: migrationsTableQuoted,
 		StatementTimeout:      time.Duration(statementTimeout) * time.Millisecond,
+		LockTimeout:           time.Duration(lockTimeout) * time.Millisecond,
 		MultiStatementEnabled: multiStatementEnabled,
 		MultiStatementMaxSize: multiStatementMaxSize,
+		CopyDir:               copyDir,
 	})
 
 	if err != nil {
@@ -232,10 +279,17 @@ func (p *Postgres) Close() error {
 	return nil
 }
 
+// advisoryLockID returns the key of the advisory lock. It includes the schema
+// and name of the migrations table, so that migrating different schemas of
+// one database doesn't serialize.
+func (p *Postgres) advisoryLockID() (string, error) {
+	return database.GenerateAdvisoryLockId(p.config.DatabaseName, p.config.migrationsSchemaName, p.config.migrationsTableName)
+}
+
 // https://www.postgresql.org/docs/9.6/static/explicit-locking.html#ADVISORY-LOCKS
 func (p *Postgres) Lock() error {
 	return database.CasRestoreOnErr(&p.isLocked, false, true, database.ErrLocked, func() error {
-		aid, err := database.GenerateAdvisoryLockId(p.config.DatabaseName, p.config.migrationsSchemaName, p.config.migrationsTableName)
+		aid, err := p.advisoryLockID()
 		if err != nil {
 			return err
 		}
@@ -250,9 +304,34 @@ func (p *Postgres) Lock() error {
 	})
 }
 
+// TryLock is part of database.TryLocker. It returns false instead of waiting
+// if another session holds the advisory lock.
+func (p *Postgres) TryLock() (bool, error) {
+	if !p.isLocked.CAS(false, true) {
+		return false, database.ErrLocked
+	}
+
+	aid, err := p.advisoryLockID()
+	if err != nil {
+		p.isLocked.Store(false)
+		return false, err
+	}
+
+	var locked bool
+	query := `SELECT pg_try_advisory_lock($1)`
+	if err := p.conn.QueryRowContext(context.Background(), query, aid).Scan(&locked); err != nil {
+		p.isLocked.Store(false)
+		return false, &database.Error{OrigErr: err, Err: "try lock failed", Query: []byte(query)}
+	}
+	if !locked {
+		p.isLocked.Store(false)
+	}
+	return locked, nil
+}
+
 func (p *Postgres) Unlock() error {
 	return database.CasRestoreOnErr(&p.isLocked, true, false, database.ErrNotLocked, func() error {
-		aid, err := database.GenerateAdvisoryLockId(p.config.DatabaseName, p.config.migrationsSchemaName, p.config.migrationsTableName)
+		aid, err := p.advisoryLockID()
 		if err != nil {
 			return err
 		}
@@ -266,10 +345,16 @@ func (p *Postgres) Unlock() error {
 }
 
 func (p *Postgres) Run(migration io.Reader) error {
+	return p.RunContext(context.Background(), migration)
+}
+
+// RunContext is part of database.RunnerContext. The running statement is
+// canceled on the server once ctx is done.
+func (p *Postgres) RunContext(ctx context.Context, migration io.Reader) error {
 	if p.config.MultiStatementEnabled {
 		var err error
 		if e := multistmt.Parse(migration, multiStmtDelimiter, p.config.MultiStatementMaxSize, func(m []byte) bool {
-			if err = p.runStatement(m); err != nil {
+			if err = p.runStatement(ctx, m); err != nil {
 				return false
 			}
 			return true
@@ -282,28 +367,56 @@ func (p *Postgres) Run(migration io.Reader) error {
 	if err != nil {
 		return err
 	}
-	return p.runStatement(migr)
+	return p.runStatement(ctx, migr)
 }
 
-func (p *Postgres) runStatement(statement []byte) error {
-	ctx := context.Background()
+func (p *Postgres) runStatement(ctx context.Context, statement []byte) error {
+	if p.config.CopyDir != "" {
+		return p.runCopyStatement(ctx, statement)
+	}
+	return p.execStatement(ctx, statement)
+}
+
+// statementContext returns the context of a statement derived from ctx,
+// bounded by StatementTimeout if set.
+func (p *Postgres) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
 	if p.config.StatementTimeout != 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, p.config.StatementTimeout)
-		defer cancel()
+		return context.WithTimeout(ctx, p.config.StatementTimeout)
 	}
+	return context.WithCancel(ctx)
+}
+
+// timeoutSettings returns the SET LOCAL statements applying LockTimeout
+// and StatementTimeout on the server, empty if neither is set.
+func (p *Postgres) timeoutSettings() string {
+	var settings string
+	if p.config.LockTimeout > 0 {
+		settings += fmt.Sprintf("SET LOCAL lock_timeout = %d; ", p.config.LockTimeout.Milliseconds())
+	}
+	if p.config.StatementTimeout > 0 {
+		settings += fmt.Sprintf("SET LOCAL statement_timeout = %d; ", p.config.StatementTimeout.Milliseconds())
+	}
+	return settings
+}
+
+func (p *Postgres) execStatement(ctx context.Context, statement []byte) error {
+	ctx, cancel := p.statementContext(ctx)
+	defer cancel()
 	query := string(statement)
 	if strings.TrimSpace(query) == "" {
 		return nil
 	}
-	if _, err := p.conn.ExecContext(ctx, query); err != nil {
+	// the settings are sent along with the statement, so that they only
+	// last for its implicit transaction block or the transaction of Begin
+	settings := p.timeoutSettings()
+	if _, err := p.execer().ExecContext(ctx, settings+query); err != nil {
 		if pgErr, ok := err.(*pq.Error); ok {
 			var line uint
 			var col uint
 			var lineColOK bool
 			if pgErr.Position != "" {
 				if pos, err := strconv.ParseUint(pgErr.Position, 10, 64); err == nil {
-					line, col, lineColOK = computeLineFromPos(query, int(pos))
+					line, col, lineColOK = computeLineFromPos(query, int(pos)-len(settings))
 				}
 			}
 			message := fmt.Sprintf("migration failed: %s", pgErr.Message)
@@ -356,16 +469,36 @@ func runesLastIndex(input []rune, target rune) int {
 }
 
 func (p *Postgres) SetVersion(version int, dirty bool) error {
+	if p.tx != nil {
+		if err := p.setVersion(p.tx, version, dirty); err != nil {
+			return err
+		}
+		return nil
+	}
+
 	tx, err := p.conn.BeginTx(context.Background(), &sql.TxOptions{})
 	if err != nil {
 		return &database.Error{OrigErr: err, Err: "transaction start failed"}
 	}
 
-	query := `TRUNCATE ` + pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName)
-	if _, err := tx.Exec(query); err != nil {
+	if err := p.setVersion(tx, version, dirty); err != nil {
 		if errRollback := tx.Rollback(); errRollback != nil {
-			err = multierror.Append(err, errRollback)
+			err.OrigErr = multierror.Append(err.OrigErr, errRollback)
 		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+
+	return nil
+}
+
+// setVersion replaces the version in the migrations table within tx.
+func (p *Postgres) setVersion(tx *sql.Tx, version int, dirty bool) *database.Error {
+	query := `TRUNCATE ` + pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName)
+	if _, err := tx.Exec(query); err != nil {
 		return &database.Error{OrigErr: err, Query: []byte(query)}
 	}
 
@@ -375,20 +508,61 @@ func (p *Postgres) SetVersion(version int, dirty bool) error {
 	if version >= 0 || (version == database.NilVersion && dirty) {
 		query = `INSERT INTO ` + pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName) + ` (version, dirty) VALUES ($1, $2)`
 		if _, err := tx.Exec(query, version, dirty); err != nil {
-			if errRollback := tx.Rollback(); errRollback != nil {
-				err = multierror.Append(err, errRollback)
-			}
 			return &database.Error{OrigErr: err, Query: []byte(query)}
 		}
 	}
 
+	return nil
+}
+
+// Begin is part of database.Transactioner. Migrations run until Commit or
+// Rollback are executed in one transaction.
+func (p *Postgres) Begin() error {
+	if p.tx != nil {
+		return ErrTxStarted
+	}
+	tx, err := p.conn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+	p.tx = tx
+	return nil
+}
+
+// Commit is part of database.Transactioner.
+func (p *Postgres) Commit() error {
+	if p.tx == nil {
+		return ErrNoTx
+	}
+	tx := p.tx
+	p.tx = nil
 	if err := tx.Commit(); err != nil {
 		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
 	}
+	return nil
+}
 
+// Rollback is part of database.Transactioner.
+func (p *Postgres) Rollback() error {
+	if p.tx == nil {
+		return ErrNoTx
+	}
+	tx := p.tx
+	p.tx = nil
+	if err := tx.Rollback(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction rollback failed"}
+	}
 	return nil
 }
 
+// execer returns the transaction started by Begin, if any, or the connection.
+func (p *Postgres) execer() execer {
+	if p.tx != nil {
+		return p.tx
+	}
+	return p.conn
+}
+
 func (p *Postgres) Version() (version int, dirty bool, err error) {
 	query := `SELECT version, dirty FROM ` + pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName) + ` LIMIT 1`
 	err = p.conn.QueryRowContext(context.Background(), query).Scan(&version, &dirty)
@@ -9,15 +9,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	nurl "net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 
 	"github.com/dhui/dktest"
+	"github.com/lib/pq"
 
 	"github.com/golang-migrate/migrate/v4/database"
 	dt "github.com/golang-migrate/migrate/v4/database/testing"
@@ -132,6 +137,58 @@ func TestMigrate(t *testing.T) {
 	})
 }
 
+func TestWholeRunInTransaction(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dir := t.TempDir()
+		for name, body := range map[string]string{
+			"1_create_foo.up.sql": "CREATE TABLE foo (id int);",
+			"2_create_bar.up.sql": "CREATE TABLE bar (id int); SELECT * FROM does_not_exist;",
+		} {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+		m, err := migrate.NewWithDatabaseInstance("file://"+dir, "postgres", d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.WholeRunInTransaction = true
+
+		if err := m.Up(); err == nil {
+			t.Fatal("expected version 2 to fail")
+		}
+
+		// version 1 is rolled back with version 2
+		var exists bool
+		if err := d.(*Postgres).conn.QueryRowContext(context.Background(), "SELECT to_regclass('foo') IS NOT NULL").Scan(&exists); err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Fatal("expected table foo to be rolled back")
+		}
+		if _, _, err := m.Version(); err != migrate.ErrNilVersion {
+			t.Fatalf("expected %v, got %v", migrate.ErrNilVersion, err)
+		}
+	})
+}
+
 func TestMultipleStatements(t *testing.T) {
 	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
 		ip, port, err := c.FirstPort()
@@ -387,6 +444,64 @@ func TestMigrationTableOption(t *testing.T) {
 	})
 }
 
+func TestMigrationsTableVars(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		table := nurl.QueryEscape("schema_migrations_${ENV}")
+		p := &Postgres{}
+		// every environment has a migrations table of its own
+		for i, env := range []string{"staging", "prod"} {
+			d, err := p.Open(fmt.Sprintf("%s&x-migrations-table=%s&x-migrations-table-vars=ENV:%s", addr, table, env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name := d.(*Postgres).config.migrationsTableName; name != "schema_migrations_"+env {
+				t.Fatalf("expected schema_migrations_%s, got %s", env, name)
+			}
+			if err := d.SetVersion(i+1, false); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for i, env := range []string{"staging", "prod"} {
+			d, err := p.Open(fmt.Sprintf("%s&x-migrations-table=%s&x-migrations-table-vars=ENV:%s", addr, table, env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			version, _, err := d.Version()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != i+1 {
+				t.Errorf("expected version %v for %s, got %v", i+1, env, version)
+			}
+			var exists bool
+			query := "SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = $1 AND table_schema = (SELECT current_schema()))"
+			if err := d.(*Postgres).conn.QueryRowContext(context.Background(), query, "schema_migrations_"+env).Scan(&exists); err != nil {
+				t.Fatal(err)
+			}
+			if !exists {
+				t.Errorf("expected table schema_migrations_%s to exist", env)
+			}
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := p.Open(fmt.Sprintf("%s&x-migrations-table=%s", addr, table)); err == nil {
+			t.Fatal("expected error for undefined variable")
+		}
+	})
+}
+
 func TestFailToCreateTableWithoutPermissions(t *testing.T) {
 	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
 		ip, port, err := c.FirstPort()
@@ -602,6 +717,40 @@ func TestParallelSchema(t *testing.T) {
 	})
 }
 
+func TestAdvisoryLockID(t *testing.T) {
+	lockID := func(schemaName, tableName string) string {
+		p := &Postgres{config: &Config{
+			DatabaseName:         "postgres",
+			migrationsSchemaName: schemaName,
+			migrationsTableName:  tableName,
+		}}
+		aid, err := p.advisoryLockID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return aid
+	}
+
+	// the key of the default schema and table is unchanged
+	if aid := lockID("public", DefaultMigrationsTable); aid != "143310882" {
+		t.Errorf("expected lock id 143310882, got %v", aid)
+	}
+
+	ids := map[string]bool{}
+	for _, target := range [][2]string{
+		{"public", DefaultMigrationsTable},
+		{"foo", DefaultMigrationsTable},
+		{"bar", DefaultMigrationsTable},
+		{"public", "other_migrations"},
+	} {
+		aid := lockID(target[0], target[1])
+		if ids[aid] {
+			t.Errorf("expected distinct lock id for %v, got %v", target, aid)
+		}
+		ids[aid] = true
+	}
+}
+
 func TestPostgres_Lock(t *testing.T) {
 	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
 		ip, port, err := c.FirstPort()
@@ -642,6 +791,164 @@ func TestPostgres_Lock(t *testing.T) {
 	})
 }
 
+func TestLockTimeout(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr + "&x-lock-timeout=500")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+		if timeout := d.(*Postgres).config.LockTimeout; timeout != 500*time.Millisecond {
+			t.Fatalf("expected a lock timeout of 500ms, got %v", timeout)
+		}
+		if err := d.Run(strings.NewReader("CREATE TABLE locked (id int)")); err != nil {
+			t.Fatal(err)
+		}
+
+		// another session holds the table until the end of the test
+		db, err := sql.Open("postgres", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := tx.Rollback(); err != nil {
+				t.Error(err)
+			}
+		}()
+		if _, err := tx.Exec("LOCK TABLE locked IN ACCESS EXCLUSIVE MODE"); err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		err = d.Run(strings.NewReader("ALTER TABLE locked ADD COLUMN name text"))
+		var pgErr *pq.Error
+		if !errors.As(err, &pgErr) || pgErr.Code != "55P03" {
+			t.Fatalf("expected a lock timeout, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the migration to fail promptly, took %v", elapsed)
+		}
+	})
+}
+
+func TestRunContext(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if err := d.(*Postgres).RunContext(ctx, strings.NewReader("SELECT pg_sleep(10)")); err == nil {
+			t.Fatal("expected the migration to be canceled")
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the migration to fail promptly, took %v", elapsed)
+		}
+
+		// the connection is still usable afterwards
+		if err := d.Run(strings.NewReader("SELECT 1")); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestTimeoutSettings(t *testing.T) {
+	for _, tc := range []struct {
+		config   Config
+		expected string
+	}{
+		{Config{}, ""},
+		{Config{LockTimeout: 500 * time.Millisecond}, "SET LOCAL lock_timeout = 500; "},
+		{Config{StatementTimeout: time.Minute}, "SET LOCAL statement_timeout = 60000; "},
+		{Config{LockTimeout: time.Second, StatementTimeout: time.Minute}, "SET LOCAL lock_timeout = 1000; SET LOCAL statement_timeout = 60000; "},
+	} {
+		p := &Postgres{config: &tc.config}
+		if settings := p.timeoutSettings(); settings != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, settings)
+		}
+	}
+}
+
+func TestPostgres_TryLock(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		var drivers []*Postgres
+		for i := 0; i < 2; i++ {
+			d, err := p.Open(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := d.Close(); err != nil {
+					t.Error(err)
+				}
+			}()
+			drivers = append(drivers, d.(*Postgres))
+		}
+		first, second := drivers[0], drivers[1]
+
+		if locked, err := first.TryLock(); err != nil || !locked {
+			t.Fatalf("expected lock to be acquired, got %v, %v", locked, err)
+		}
+
+		// the second session doesn't wait for the first one
+		if locked, err := second.TryLock(); err != nil || locked {
+			t.Fatalf("expected lock to be held, got %v, %v", locked, err)
+		}
+
+		if err := first.Unlock(); err != nil {
+			t.Fatal(err)
+		}
+		if locked, err := second.TryLock(); err != nil || !locked {
+			t.Fatalf("expected lock to be acquired, got %v, %v", locked, err)
+		}
+		if err := second.Unlock(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
 func TestWithInstance_Concurrent(t *testing.T) {
 	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
 		ip, port, err := c.FirstPort()
@@ -722,6 +1029,95 @@ func TestWithConnection(t *testing.T) {
 	})
 }
 
+func TestCopy(t *testing.T) {
+	const rows = 100000
+
+	dir := t.TempDir()
+	var data strings.Builder
+	data.WriteString("id,name\n")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&data, "%d,name %d\n", i, i)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "rows.csv"), []byte(data.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var inserts strings.Builder
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&inserts, "INSERT INTO insert_rows (id, name) VALUES (%d, 'name %d');\n", i, i)
+	}
+
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port, "x-copy-dir="+dir)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		start := time.Now()
+		if err := d.Run(strings.NewReader(`CREATE TABLE copy_rows (id integer, name text);
+--migrate:copy copy_rows FROM rows.csv
+CREATE INDEX copy_rows_id ON copy_rows (id);`)); err != nil {
+			t.Fatal(err)
+		}
+		copyTime := time.Since(start)
+
+		start = time.Now()
+		if err := d.Run(strings.NewReader("CREATE TABLE insert_rows (id integer, name text);\n" + inserts.String())); err != nil {
+			t.Fatal(err)
+		}
+		insertTime := time.Since(start)
+		t.Logf("loaded %d rows with COPY in %v, with INSERT in %v", rows, copyTime, insertTime)
+
+		for _, table := range []string{"copy_rows", "insert_rows"} {
+			var count int
+			if err := d.(*Postgres).conn.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != rows {
+				t.Errorf("expected %d rows in %v, got %d", rows, table, count)
+			}
+		}
+
+		// a missing data file fails the migration
+		if err := d.Run(strings.NewReader("--migrate:copy copy_rows FROM missing.csv")); err == nil {
+			t.Error("expected the missing data file to fail the migration")
+		}
+	})
+}
+
+func TestCopyDirectives(t *testing.T) {
+	statement := []byte(`CREATE TABLE countries (code text, name text);
+--migrate:copy countries FROM data/countries.csv
+  --MIGRATE:COPY public.cities from cities.csv
+-- migrate:copy not_a_directive FROM x.csv
+SELECT 1;`)
+	directives := copyDirectives(statement)
+	if len(directives) != 2 {
+		t.Fatalf("expected 2 directives, got %+v", directives)
+	}
+	expected := []struct{ table, file, line string }{
+		{"countries", "data/countries.csv", "--migrate:copy countries FROM data/countries.csv"},
+		{"public.cities", "cities.csv", "  --MIGRATE:COPY public.cities from cities.csv"},
+	}
+	for i, e := range expected {
+		d := directives[i]
+		if d.table != e.table || d.file != e.file || string(statement[d.start:d.end]) != e.line {
+			t.Errorf("expected %+v, got %+v (%q)", e, d, statement[d.start:d.end])
+		}
+	}
+}
+
 func Test_computeLineFromPos(t *testing.T) {
 	testcases := []struct {
 		pos      int
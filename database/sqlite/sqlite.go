@@ -9,6 +9,7 @@ import (
 	nurl "net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
@@ -31,6 +32,22 @@ type Config struct {
 	MigrationsTable string
 	DatabaseName    string
 	NoTxWrap        bool
+	// BusyTimeout sets PRAGMA busy_timeout if not zero. The pragma applies
+	// to a single connection, so the pool of instance should be limited to
+	// one connection.
+	BusyTimeout time.Duration
+	// JournalMode sets PRAGMA journal_mode if not empty, e.g. to WAL.
+	JournalMode string
+}
+
+// journalModes are the values accepted for PRAGMA journal_mode.
+var journalModes = map[string]bool{
+	"DELETE":   true,
+	"TRUNCATE": true,
+	"PERSIST":  true,
+	"MEMORY":   true,
+	"WAL":      true,
+	"OFF":      true,
 }
 
 type Sqlite struct {
@@ -57,12 +74,42 @@ func WithInstance(instance *sql.DB, config *Config) (database.Driver, error) {
 		db:     instance,
 		config: config,
 	}
+	if err := mx.setPragmas(); err != nil {
+		return nil, err
+	}
 	if err := mx.ensureVersionTable(); err != nil {
 		return nil, err
 	}
 	return mx, nil
 }
 
+// setPragmas applies BusyTimeout and JournalMode.
+func (m *Sqlite) setPragmas() error {
+	if m.config.BusyTimeout != 0 {
+		query := fmt.Sprintf("PRAGMA busy_timeout = %d", m.config.BusyTimeout.Milliseconds())
+		if _, err := m.db.Exec(query); err != nil {
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+	}
+
+	if m.config.JournalMode != "" {
+		mode := strings.ToUpper(m.config.JournalMode)
+		if !journalModes[mode] {
+			return fmt.Errorf("invalid journal mode: %s", m.config.JournalMode)
+		}
+		query := "PRAGMA journal_mode = " + mode
+		var result string
+		if err := m.db.QueryRow(query).Scan(&result); err != nil {
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		// e.g. in-memory databases don't support WAL
+		if !strings.EqualFold(result, mode) {
+			return fmt.Errorf("unable to set journal mode %s, got %s", mode, result)
+		}
+	}
+	return nil
+}
+
 // ensureVersionTable checks if versions table exists and, if not, creates it.
 // Note that this function locks the database, which deviates from the usual
 // convention of "caller locks" in the Sqlite type.
@@ -118,10 +165,23 @@ func (m *Sqlite) Open(url string) (database.Driver, error) {
 		}
 	}
 
+	var busyTimeout time.Duration
+	if v := qv.Get("x-busy-timeout"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("x-busy-timeout: %s", err)
+		}
+		busyTimeout = time.Duration(ms) * time.Millisecond
+		// the pragma applies to a single connection
+		db.SetMaxOpenConns(1)
+	}
+
 	mx, err := WithInstance(db, &Config{
 		DatabaseName:    purl.Path,
 		MigrationsTable: migrationsTable,
 		NoTxWrap:        noTxWrap,
+		BusyTimeout:     busyTimeout,
+		JournalMode:     qv.Get("x-journal-mode"),
 	})
 	if err != nil {
 		return nil, err
@@ -168,7 +228,7 @@ func (m *Sqlite) Drop() (err error) {
 			}
 		}
 		query := "VACUUM"
-		_, err = m.db.Query(query)
+		_, err = m.db.Exec(query)
 		if err != nil {
 			return &database.Error{OrigErr: err, Query: []byte(query)}
 		}
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -119,6 +121,89 @@ func TestNoTxWrapInvalidValue(t *testing.T) {
 	}
 }
 
+func TestPragmas(t *testing.T) {
+	dir := t.TempDir()
+	t.Logf("DB path : %s\n", filepath.Join(dir, "sqlite.db"))
+	p := &Sqlite{}
+	addr := fmt.Sprintf("sqlite://%s?x-busy-timeout=5000&x-journal-mode=wal", filepath.Join(dir, "sqlite.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	db := d.(*Sqlite).db
+
+	var busyTimeout int
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5000, busyTimeout)
+
+	var journalMode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "wal", journalMode)
+}
+
+func TestDropWithBusyTimeout(t *testing.T) {
+	dir := t.TempDir()
+	p := &Sqlite{}
+	// x-busy-timeout limits the driver to a single connection
+	addr := fmt.Sprintf("sqlite://%s?x-busy-timeout=5000", filepath.Join(dir, "sqlite.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := d.Run(strings.NewReader("CREATE TABLE t (Qty int, Name string);")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetVersion(1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		if err := d.Drop(); err != nil {
+			done <- err
+			return
+		}
+		_, _, err := d.Version()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Version blocked after Drop")
+	}
+}
+
+func TestPragmasInvalidValue(t *testing.T) {
+	dir := t.TempDir()
+	p := &Sqlite{}
+	_, err := p.Open(fmt.Sprintf("sqlite://%s?x-busy-timeout=soon", filepath.Join(dir, "sqlite.db")))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "x-busy-timeout")
+	}
+	_, err = p.Open(fmt.Sprintf("sqlite://%s?x-journal-mode=fast", filepath.Join(dir, "sqlite.db")))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid journal mode")
+	}
+}
+
 func TestMigrateWithDirectoryNameContainsWhitespaces(t *testing.T) {
 	dir := t.TempDir()
 	dbPath := filepath.Join(dir, "sqlite.db")
@@ -1,6 +1,7 @@
 package stub
 
 import (
+	"errors"
 	"go.uber.org/atomic"
 	"io"
 	"io/ioutil"
@@ -20,8 +21,12 @@ type Stub struct {
 	MigrationSequence []string
 	LastRunMigration  []byte // todo: make []string
 	IsDirty           bool
+	AppliedChecksums  map[uint]string
 	isLocked          atomic.Bool
 
+	// tx holds the state restored by Rollback
+	tx *Stub
+
 	Config *Config
 }
 
@@ -56,6 +61,10 @@ func (s *Stub) Lock() error {
 	return nil
 }
 
+func (s *Stub) TryLock() (bool, error) {
+	return s.isLocked.CAS(false, true), nil
+}
+
 func (s *Stub) Unlock() error {
 	if !s.isLocked.CAS(true, false) {
 		return database.ErrNotLocked
@@ -83,6 +92,57 @@ func (s *Stub) Version() (version int, dirty bool, err error) {
 	return s.CurrentVersion, s.IsDirty, nil
 }
 
+func (s *Stub) SetChecksum(version uint, checksum string) error {
+	if s.AppliedChecksums == nil {
+		s.AppliedChecksums = make(map[uint]string)
+	}
+	s.AppliedChecksums[version] = checksum
+	return nil
+}
+
+func (s *Stub) Checksums() (map[uint]string, error) {
+	checksums := make(map[uint]string, len(s.AppliedChecksums))
+	for version, checksum := range s.AppliedChecksums {
+		checksums[version] = checksum
+	}
+	return checksums, nil
+}
+
+func (s *Stub) Begin() error {
+	if s.tx != nil {
+		return errors.New("transaction already started")
+	}
+	s.tx = &Stub{
+		CurrentVersion:    s.CurrentVersion,
+		MigrationSequence: append(make([]string, 0, len(s.MigrationSequence)), s.MigrationSequence...),
+		LastRunMigration:  s.LastRunMigration,
+		IsDirty:           s.IsDirty,
+	}
+	s.tx.AppliedChecksums, _ = s.Checksums()
+	return nil
+}
+
+func (s *Stub) Commit() error {
+	if s.tx == nil {
+		return errors.New("no transaction started")
+	}
+	s.tx = nil
+	return nil
+}
+
+func (s *Stub) Rollback() error {
+	if s.tx == nil {
+		return errors.New("no transaction started")
+	}
+	s.CurrentVersion = s.tx.CurrentVersion
+	s.MigrationSequence = s.tx.MigrationSequence
+	s.LastRunMigration = s.tx.LastRunMigration
+	s.IsDirty = s.tx.IsDirty
+	s.AppliedChecksums = s.tx.AppliedChecksums
+	s.tx = nil
+	return nil
+}
+
 const DROP = "DROP"
 
 func (s *Stub) Drop() error {
@@ -21,6 +21,7 @@ func TestMigrate(t *testing.T, m *migrate.Migrate) {
 // Similar to TestDrop(), but tests the dropping mechanism through the Migrate logic instead, to check for
 // double-locking during the Drop logic.
 func TestMigrateDrop(t *testing.T, m *migrate.Migrate) {
+	m.SetDropConfirm(true)
 	if err := m.Drop(); err != nil {
 		t.Fatal(err)
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go.uber.org/atomic"
 	"hash/crc32"
+	"regexp"
 	"strings"
 )
 
@@ -31,3 +32,57 @@ func CasRestoreOnErr(lock *atomic.Bool, o, n bool, casErr error, f func() error)
 	}
 	return nil
 }
+
+var (
+	// tableNameVarRegex matches the ${NAME} tokens of ExpandTableName.
+	tableNameVarRegex = regexp.MustCompile(`\$\{(\w+)\}`)
+	// tableNameValueRegex matches the values allowed for a token.
+	tableNameValueRegex = regexp.MustCompile(`^\w+$`)
+)
+
+// ExpandTableName replaces the ${NAME} tokens of a migrations table name
+// with their values in vars, e.g. "schema_migrations_${ENV}" becomes
+// "schema_migrations_staging" for {"ENV": "staging"}. Undefined tokens and
+// values other than letters, digits and underscores are an error, so the
+// expanded name is as valid as name.
+func ExpandTableName(name string, vars map[string]string) (string, error) {
+	var err error
+	expanded := tableNameVarRegex.ReplaceAllStringFunc(name, func(token string) string {
+		if err != nil {
+			return token
+		}
+		key := tableNameVarRegex.FindStringSubmatch(token)[1]
+		value, ok := vars[key]
+		if !ok {
+			err = fmt.Errorf("undefined variable %s in migrations table name %q", key, name)
+			return token
+		}
+		if !tableNameValueRegex.MatchString(value) {
+			err = fmt.Errorf("invalid value %q of variable %s in migrations table name %q", value, key, name)
+			return token
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// ParseTableNameVars parses the variables of ExpandTableName from a list
+// like "ENV:staging,REGION:eu", as set by the x-migrations-table-vars
+// query parameter of drivers.
+func ParseTableNameVars(s string) (map[string]string, error) {
+	vars := make(map[string]string)
+	if s == "" {
+		return vars, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid migrations table variable %q, expected NAME:value", pair)
+		}
+		vars[kv[0]] = kv[1]
+	}
+	return vars, nil
+}
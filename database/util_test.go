@@ -104,3 +104,54 @@ func TestCasRestoreOnErr(t *testing.T) {
 		})
 	}
 }
+
+func TestExpandTableName(t *testing.T) {
+	vars := map[string]string{"ENV": "staging", "REGION": "eu_west", "BAD": "prod; DROP TABLE x"}
+	testcases := []struct {
+		name     string
+		expected string // empty string signifies that an error is expected
+	}{
+		{name: "schema_migrations", expected: "schema_migrations"},
+		{name: "schema_migrations_${ENV}", expected: "schema_migrations_staging"},
+		{name: "${REGION}.schema_migrations_${ENV}", expected: "eu_west.schema_migrations_staging"},
+		{name: "schema_migrations_${UNDEFINED}"},
+		{name: "schema_migrations_${BAD}"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			expanded, err := ExpandTableName(tc.name, vars)
+			if tc.expected == "" {
+				if err == nil {
+					t.Errorf("expected error, got %q", expanded)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expanded != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, expanded)
+			}
+		})
+	}
+}
+
+func TestParseTableNameVars(t *testing.T) {
+	vars, err := ParseTableNameVars("ENV:staging,REGION:eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 2 || vars["ENV"] != "staging" || vars["REGION"] != "eu" {
+		t.Errorf("unexpected vars %v", vars)
+	}
+
+	if vars, err := ParseTableNameVars(""); err != nil || len(vars) != 0 {
+		t.Errorf("expected no vars, got %v, %v", vars, err)
+	}
+	for _, s := range []string{"ENV", ":staging", "ENV:staging,"} {
+		if _, err := ParseTableNameVars(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
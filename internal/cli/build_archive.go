@@ -0,0 +1,8 @@
+//go:build archive
+// +build archive
+
+package cli
+
+import (
+	_ "github.com/golang-migrate/migrate/v4/source/archive"
+)
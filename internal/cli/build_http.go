@@ -0,0 +1,8 @@
+//go:build http
+// +build http
+
+package cli
+
+import (
+	_ "github.com/golang-migrate/migrate/v4/source/http"
+)
@@ -196,6 +196,8 @@ func downCmd(m *migrate.Migrate, limit int) error {
 }
 
 func dropCmd(m *migrate.Migrate) error {
+	// the user confirmed at the prompt or with -f
+	m.SetDropConfirm(true)
 	if err := m.Drop(); err != nil {
 		return err
 	}
@@ -10,3 +10,21 @@ type Logger interface {
 	// Verbose should return true when verbose logging output is wanted
 	Verbose() bool
 }
+
+// LeveledLogger is an optional interface a Logger can implement to receive
+// migration events with a severity and key-value pairs, e.g. to route them
+// into structured logging. Migrate uses it in place of Printf when present.
+type LeveledLogger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// LoggerSetter is an optional interface a database driver can implement to
+// log through the Logger of Migrate, e.g. output of the database produced
+// while migrations run. Migrate passes its Log, if set, to SetLogger before
+// running migrations.
+type LoggerSetter interface {
+	SetLogger(logger Logger)
+}
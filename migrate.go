@@ -5,9 +5,22 @@
 package migrate
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,6 +47,29 @@ var (
 	ErrInvalidVersion = errors.New("version must be >= -1")
 	ErrLocked         = errors.New("database locked")
 	ErrLockTimeout    = errors.New("timeout: can't acquire database lock")
+
+	// ErrDropNotConfirmed is returned by Drop unless SetDropConfirm(true)
+	// was called.
+	ErrDropNotConfirmed = errors.New("drop not confirmed, see SetDropConfirm")
+
+	// ErrDriverInUse is returned when locking if another Migrate instance
+	// wrapping the same database driver holds the lock.
+	ErrDriverInUse = errors.New("database driver in use by another Migrate instance")
+
+	// ErrVersionNotFound is returned by ForceChecked if the source has no
+	// migration of the version.
+	ErrVersionNotFound = errors.New("version not found in source")
+
+	// ErrAlreadyTracked is returned by Baseline if the database records
+	// a version already.
+	ErrAlreadyTracked = errors.New("database version already recorded")
+)
+
+// lockedDrivers maps the database drivers locked by a Migrate instance to
+// that instance, to detect instances sharing a driver.
+var (
+	lockedDriversMu sync.Mutex
+	lockedDrivers   = make(map[database.Driver]*Migrate)
 )
 
 // ErrShortLimit is an error returned when not enough migrations
@@ -47,6 +83,107 @@ func (e ErrShortLimit) Error() string {
 	return fmt.Sprintf("limit %v short", e.Short)
 }
 
+// ErrRangeStart is returned by ApplyRange if the database isn't at the
+// version preceding the range, so that applying it would skip versions.
+type ErrRangeStart struct {
+	From uint
+	// Expected is the version preceding From, -1 if From is the first one.
+	Expected int
+	Version  int
+}
+
+// Error implements the error interface.
+func (e ErrRangeStart) Error() string {
+	return fmt.Sprintf("range starting at version %v requires database version %v, but it is at %v", e.From, e.Expected, e.Version)
+}
+
+// ErrDatabaseAheadOfSource is returned if the database is at a version
+// after the latest version of the source, e.g. because an older release
+// with fewer migrations is run against it. Migrating such a database
+// would fail or, worse, downgrade it unnoticed.
+type ErrDatabaseAheadOfSource struct {
+	Version int
+	Latest  uint
+}
+
+// Error implements the error interface.
+func (e ErrDatabaseAheadOfSource) Error() string {
+	return fmt.Sprintf("database version %v is higher than any available source version, the latest is %v", e.Version, e.Latest)
+}
+
+// ErrVersionCollision is returned by NewWithMultiSource if two sources hold
+// migrations of the same version. Source and Other are the positions of the
+// sources in the slice passed to NewWithMultiSource.
+type ErrVersionCollision struct {
+	Version uint
+	Source  int
+	Other   int
+}
+
+// Error implements the error interface.
+func (e ErrVersionCollision) Error() string {
+	return fmt.Sprintf("version %v is in source %v and source %v", e.Version, e.Source, e.Other)
+}
+
+// ErrMigrationFailed is returned if the database driver failed to run a
+// migration. It names the migration by version, direction and identifier,
+// i.e. the name part of its file name.
+type ErrMigrationFailed struct {
+	Version    uint
+	Direction  Direction
+	Identifier string
+	// Err is the error returned by the database driver.
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrMigrationFailed) Error() string {
+	return fmt.Sprintf("migration %v failed in version %v (%v): %v", e.Identifier, e.Version, e.Direction, e.Err)
+}
+
+// Unwrap returns the error returned by the database driver.
+func (e ErrMigrationFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrTransactionsNotSupported is returned if WholeRunInTransaction is set,
+// but the database driver doesn't implement database.Transactioner.
+var ErrTransactionsNotSupported = errors.New("database driver doesn't support running migrations in one transaction")
+
+// ErrChecksumsNotSupported is returned by Verify if the database driver
+// doesn't implement database.Checksummer.
+var ErrChecksumsNotSupported = errors.New("database driver doesn't support checksums")
+
+// ChecksumMismatch describes an applied migration whose checksum
+// doesn't match the checksum of its up migration in the source.
+type ChecksumMismatch struct {
+	Version uint
+	// Applied is the checksum recorded when the migration was applied.
+	Applied string
+	// Source is the checksum of the migration in the source,
+	// empty if the up migration doesn't exist anymore.
+	Source string
+}
+
+// ErrChecksumMismatch is returned by Verify if applied migrations
+// have been changed in the source.
+type ErrChecksumMismatch struct {
+	Mismatches []ChecksumMismatch
+}
+
+// Error implements the error interface.
+func (e ErrChecksumMismatch) Error() string {
+	details := make([]string, 0, len(e.Mismatches))
+	for _, mismatch := range e.Mismatches {
+		if mismatch.Source == "" {
+			details = append(details, fmt.Sprintf("version %v: applied %v, missing in source", mismatch.Version, mismatch.Applied))
+		} else {
+			details = append(details, fmt.Sprintf("version %v: applied %v, source %v", mismatch.Version, mismatch.Applied, mismatch.Source))
+		}
+	}
+	return fmt.Sprintf("checksum mismatch of applied migrations: %v", strings.Join(details, "; "))
+}
+
 type ErrDirty struct {
 	Version int
 }
@@ -70,23 +207,263 @@ type Migrate struct {
 	GracefulStop chan bool
 	isLockedMu   *sync.Mutex
 
-	isGracefulStop bool
-	isLocked       bool
+	// isGracefulStopMu guards isGracefulStop, set by the reader and the
+	// runner of migrations
+	isGracefulStopMu *sync.Mutex
+	isGracefulStop   bool
+	isLocked         bool
+
+	// stoppedAt is the database version when the last run was stopped
+	// through GracefulStop, see StoppedAt
+	stopped   bool
+	stoppedAt int
 
 	// PrefetchMigrations defaults to DefaultPrefetchMigrations,
-	// but can be set per Migrate instance.
+	// but can be set per Migrate instance. It also bounds the number
+	// of migrations opened concurrently.
 	PrefetchMigrations uint
 
 	// LockTimeout defaults to DefaultLockTimeout,
 	// but can be set per Migrate instance.
 	LockTimeout time.Duration
+
+	// OnLock and OnUnlock are called with the current time once the database
+	// lock was acquired and released, e.g. to debug lock contention.
+	// OnUnlock is called if the run failed, too, but not if releasing the
+	// lock failed. Either can be nil.
+	OnLock   func(at time.Time)
+	OnUnlock func(at time.Time)
+
+	// LockReads makes Version, Pending and Status take the migration lock,
+	// like the operations changing the database do, so that they fail or
+	// wait while a migration is running. By default they read without
+	// locking, reporting a running migration as dirty version.
+	LockReads bool
+
+	// NoChangeIsNil makes Migrate, Steps, Up, Down and Run return nil
+	// instead of ErrNoChange if there is nothing to do. ApplyOne still
+	// returns ErrNoChange.
+	NoChangeIsNil bool
+
+	// WholeRunInTransaction runs all migrations of Migrate, Steps, Up, Down
+	// or Run in one transaction, so that they are committed together or,
+	// if one fails, all rolled back, leaving the database clean at its
+	// previous version. The database driver must implement
+	// database.Transactioner, otherwise ErrTransactionsNotSupported is
+	// returned.
+	WholeRunInTransaction bool
+
+	// VersionComparator orders the versions of the source, e.g. if they
+	// encode semantic versions. It defaults to numeric order and must
+	// be set before the first migration is run.
+	VersionComparator VersionComparator
+
+	// versions caches the source versions selected by the version filter
+	// and sorted by VersionComparator
+	versionsOnce sync.Once
+	versions     []uint
+	versionsErr  error
+
+	// beforeHook and afterHook are set by SetHooks
+	beforeHook Hook
+	afterHook  Hook
+
+	// metricsSink is set by SetMetricsSink
+	metricsSink MetricsSink
+
+	// runTimeout is set by SetRunTimeout
+	runTimeout time.Duration
+
+	// progress is the channel returned by Progress for the next run
+	progress chan Progress
+
+	// retryAttempts and retryBackoff are set by SetRetry
+	retryAttempts int
+	retryBackoff  func(attempt int) time.Duration
+
+	// clock returns the current time, see SetClock
+	clock func() time.Time
+
+	// versionStore records the versions instead of the database driver if
+	// set, see SetVersionStore
+	versionStore VersionStore
+
+	// dropConfirmed allows Drop, see SetDropConfirm
+	dropConfirmed bool
+
+	// versionFilter selects the source versions, see SetVersionFilter
+	versionFilter func(version uint) bool
+
+	// contentTransformer is set by SetContentTransformer
+	contentTransformer ContentTransformer
+}
+
+// VersionComparator reports whether version a sorts before version b.
+type VersionComparator func(a, b uint) bool
+
+// Direction is the direction a migration is run in, either source.Up or source.Down.
+type Direction = source.Direction
+
+// Hook is a function run around a migration, see SetHooks.
+type Hook func(version uint, direction Direction) error
+
+// SetHooks sets functions run before and after the database driver runs a
+// migration, e.g. to disable and re-enable triggers. Migrations without a
+// migration file don't run hooks. An error returned by before aborts
+// the migration before the database is changed. An error returned by
+// after fails the migration, which ran already, and leaves the database
+// dirty. Either hook can be nil.
+func (m *Migrate) SetHooks(before, after Hook) {
+	m.beforeHook = before
+	m.afterHook = after
+}
+
+// progressBufferSize is the capacity of the channel returned by Progress.
+const progressBufferSize = 64
+
+// Progress is sent on the channel returned by Migrate.Progress
+// before a migration starts.
+type Progress struct {
+	// Current is the position of the migration within the run, starting at 1.
+	Current int
+	// Total is the number of migrations of the run.
+	Total int
+
+	Version   uint
+	Direction Direction
+}
+
+// Progress returns a channel receiving a Progress before each migration of
+// the next run of Migrate, Steps, Up, Down or Run. The channel is closed
+// when the run ends. Progress is dropped rather than blocking the run if
+// the channel isn't read. In order to know the total, all migrations of
+// the run are read from the source before the first one is run.
+func (m *Migrate) Progress() <-chan Progress {
+	m.progress = make(chan Progress, progressBufferSize)
+	return m.progress
+}
+
+// VersionStore records the version of the migrated database, see
+// SetVersionStore. Every database.Driver is a VersionStore, recording the
+// version in its migrations table.
+type VersionStore interface {
+	// Version returns the recorded version and whether it's dirty,
+	// database.NilVersion if no version is recorded.
+	Version() (version int, dirty bool, err error)
+
+	// SetVersion records version, dirty if its migration is running or
+	// failed.
+	SetVersion(version int, dirty bool) error
+
+	// Drop removes the recorded versions. Mind that Drop of a
+	// database.Driver used as VersionStore drops every table of its database.
+	Drop() error
+}
+
+// SetVersionStore records the versions with store instead of the database
+// driver, e.g. in an audit database separate from the migrated one. The
+// migrations still run and the lock is still taken with the database
+// driver. If store implements database.Checksummer, it records the
+// checksums, too. As the versions aren't recorded in the transactions of
+// the database driver, a failing run of WholeRunInTransaction may leave
+// them dirty. store must be set before the first migration is run, nil
+// restores the database driver.
+func (m *Migrate) SetVersionStore(store VersionStore) {
+	m.versionStore = store
+}
+
+// store returns the VersionStore set by SetVersionStore, or else the
+// database driver.
+func (m *Migrate) store() VersionStore {
+	if m.versionStore != nil {
+		return m.versionStore
+	}
+	return m.databaseDrv
+}
+
+// MetricsSink receives the duration of a migration and the error it failed
+// with, nil on success, see SetMetricsSink.
+type MetricsSink func(version uint, direction Direction, duration time.Duration, err error)
+
+// SetMetricsSink sets a function called once every migration completed,
+// successfully or not, e.g. to export its duration. sink can be nil.
+func (m *Migrate) SetMetricsSink(sink MetricsSink) {
+	m.metricsSink = sink
+}
+
+// SetRunTimeout bounds the time a run may take, from acquiring the lock to
+// the last migration, e.g. for a hard limit in CI. Once d elapsed the run
+// stops before the next migration and returns context.DeadlineExceeded,
+// leaving the database at the version of the last migration applied. The
+// running migration itself is only interrupted by drivers implementing
+// database.RunnerContext, leaving the database dirty. Drivers implementing
+// database.LockerContext stop waiting for the lock. Zero disables the
+// timeout, which is the default.
+func (m *Migrate) SetRunTimeout(d time.Duration) {
+	m.runTimeout = d
+}
+
+// runContext returns ctx bounded by the timeout set by SetRunTimeout.
+func (m *Migrate) runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.runTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, m.runTimeout)
+}
+
+// SetRetry retries acquiring the lock and reading the database version up to
+// attempts times if they fail with a transient error, e.g. because the
+// database isn't ready yet. Before retry attempt n, starting at 1, Migrate
+// waits for backoff(n), which can be nil. Errors are transient if reported
+// so by IsTransient or by the database driver, if it implements
+// database.TransientErrorer. New connects to the database before SetRetry
+// can be called, use NewWithRetry to retry the initial connection as well.
+func (m *Migrate) SetRetry(attempts int, backoff func(attempt int) time.Duration) {
+	m.retryAttempts = attempts
+	m.retryBackoff = backoff
+}
+
+// SetClock sets the function returning the current time wherever Migrate
+// needs it, i.e. for the timestamps of migrations, OnLock and OnUnlock and
+// the durations reported to the metrics sink. It defaults to time.Now and
+// is meant for deterministic tests.
+func (m *Migrate) SetClock(clock func() time.Time) {
+	m.clock = clock
+}
+
+// now returns the current time of the clock set by SetClock.
+func (m *Migrate) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock()
+}
+
+// IsTransient reports whether err is likely to go away on retry, which is
+// the case for ErrLockTimeout and driver.ErrBadConn.
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrLockTimeout) || errors.Is(err, driver.ErrBadConn)
 }
 
 // New returns a new Migrate instance from a source URL and a database URL.
 // The URL scheme is defined by each driver.
 func New(sourceURL, databaseURL string) (*Migrate, error) {
+	return newFromURLs(newCommon(), sourceURL, databaseURL)
+}
+
+// NewWithRetry is New, which retries connecting to the database like
+// SetRetry(attempts, backoff) does, e.g. if it isn't ready yet in CI. Opening
+// the database is retried on errors reported by IsTransient and on network
+// errors, such as a refused connection. The returned Migrate retries as set
+// by SetRetry.
+func NewWithRetry(sourceURL, databaseURL string, attempts int, backoff func(attempt int) time.Duration) (*Migrate, error) {
 	m := newCommon()
+	m.SetRetry(attempts, backoff)
+	return newFromURLs(m, sourceURL, databaseURL)
+}
 
+// newFromURLs opens the source and the database of m from their URLs.
+func newFromURLs(m *Migrate, sourceURL, databaseURL string) (*Migrate, error) {
 	sourceName, err := iurl.SchemeFromURL(sourceURL)
 	if err != nil {
 		return nil, err
@@ -105,7 +482,11 @@ func New(sourceURL, databaseURL string) (*Migrate, error) {
 	}
 	m.sourceDrv = sourceDrv
 
-	databaseDrv, err := database.Open(databaseURL)
+	var databaseDrv database.Driver
+	err = m.retryIf(context.Background(), isConnectionError, func() (err error) {
+		databaseDrv, err = database.Open(databaseURL)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -114,10 +495,19 @@ func New(sourceURL, databaseURL string) (*Migrate, error) {
 	return m, nil
 }
 
+// isConnectionError reports whether err of opening a database is worth
+// retrying, i.e. it's transient by IsTransient or a network error.
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return IsTransient(err) || errors.As(err, &netErr)
+}
+
 // NewWithDatabaseInstance returns a new Migrate instance from a source URL
 // and an existing database instance. The source URL scheme is defined by each driver.
 // Use any string that can serve as an identifier during logging as databaseName.
 // You are responsible for closing the underlying database client if necessary.
+// Instances sharing databaseInstance can't hold the lock at the same time,
+// the second one to lock fails with ErrDriverInUse.
 func NewWithDatabaseInstance(sourceURL string, databaseName string, databaseInstance database.Driver) (*Migrate, error) {
 	m := newCommon()
 
@@ -182,12 +572,26 @@ func NewWithInstance(sourceName string, sourceInstance source.Driver, databaseNa
 	return m, nil
 }
 
+// NewWithMultiSource returns a new Migrate instance applying the migrations
+// of several sources, merged by version, to an existing database instance,
+// e.g. the migrations shared by all services and those of a single service.
+// The versions of the sources are listed once; a version in more than one
+// source is an ErrVersionCollision. Closing the Migrate closes all sources.
+func NewWithMultiSource(sources []source.Driver, databaseInstance database.Driver) (*Migrate, error) {
+	sourceDrv, err := newMultiSource(sources)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithInstance(multiSourceName, sourceDrv, "", databaseInstance)
+}
+
 func newCommon() *Migrate {
 	return &Migrate{
 		GracefulStop:       make(chan bool, 1),
 		PrefetchMigrations: DefaultPrefetchMigrations,
 		LockTimeout:        DefaultLockTimeout,
 		isLockedMu:         &sync.Mutex{},
+		isGracefulStopMu:   &sync.Mutex{},
 	}
 }
 
@@ -197,6 +601,7 @@ func (m *Migrate) Close() (source error, database error) {
 	sourceSrvClose := make(chan error)
 
 	m.logVerbosePrintf("Closing source and database\n")
+	m.releaseDriver()
 
 	go func() {
 		databaseSrvClose <- m.databaseDrv.Close()
@@ -212,11 +617,17 @@ func (m *Migrate) Close() (source error, database error) {
 // Migrate looks at the currently active migration version,
 // then migrates either up or down to the specified version.
 func (m *Migrate) Migrate(version uint) error {
-	if err := m.lock(); err != nil {
+	if err := m.checkTransactions(); err != nil {
+		return err
+	}
+
+	ctx, cancel := m.runContext(context.Background())
+	defer cancel()
+	if err := m.lockContext(ctx); err != nil {
 		return err
 	}
 
-	curVersion, dirty, err := m.databaseDrv.Version()
+	curVersion, dirty, err := m.databaseVersion()
 	if err != nil {
 		return m.unlockErr(err)
 	}
@@ -225,24 +636,105 @@ func (m *Migrate) Migrate(version uint) error {
 		return m.unlockErr(ErrDirty{curVersion})
 	}
 
+	if err := m.checkDatabaseVersion(curVersion); err != nil {
+		return m.unlockErr(err)
+	}
+
 	ret := make(chan interface{}, m.PrefetchMigrations)
 	go m.read(curVersion, int(version), ret)
 
-	return m.unlockErr(m.runMigrations(ret))
+	return m.unlockErr(m.runMigrationsContext(ctx, ret, m.readTotal(curVersion, int(version))))
+}
+
+// ApplyRange applies the up migrations of versions from through to, which
+// must exist in the source. The database must be at the version preceding
+// from, otherwise ErrRangeStart is returned, so that no version is skipped.
+func (m *Migrate) ApplyRange(from, to uint) error {
+	if err := m.versionExists(from); err != nil {
+		return err
+	}
+	if err := m.versionExists(to); err != nil {
+		return err
+	}
+	if m.before(int(to), int(from)) {
+		return fmt.Errorf("invalid range: version %v is before version %v", to, from)
+	}
+
+	if err := m.checkTransactions(); err != nil {
+		return err
+	}
+
+	ctx, cancel := m.runContext(context.Background())
+	defer cancel()
+	if err := m.lockContext(ctx); err != nil {
+		return err
+	}
+
+	curVersion, dirty, err := m.databaseVersion()
+	if err != nil {
+		return m.unlockErr(err)
+	}
+
+	if dirty {
+		return m.unlockErr(ErrDirty{curVersion})
+	}
+
+	expected := database.NilVersion
+	prev, err := m.prev(from)
+	if err == nil {
+		expected = int(prev)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return m.unlockErr(err)
+	}
+	if curVersion != expected {
+		return m.unlockErr(ErrRangeStart{From: from, Expected: expected, Version: curVersion})
+	}
+
+	ret := make(chan interface{}, m.PrefetchMigrations)
+	go m.read(curVersion, int(to), ret)
+
+	return m.unlockErr(m.runMigrationsContext(ctx, ret, m.readTotal(curVersion, int(to))))
+}
+
+// Goto migrates up or down to land exactly on version, which must exist in
+// the source. Unlike Migrate it doesn't return ErrNoChange if the database
+// is at version already. It refuses to migrate a dirty database.
+func (m *Migrate) Goto(version uint) error {
+	if err := m.versionExists(version); err != nil {
+		return err
+	}
+
+	if err := m.Migrate(version); err != nil && !errors.Is(err, ErrNoChange) {
+		return err
+	}
+	return nil
 }
 
 // Steps looks at the currently active migration version.
 // It will migrate up if n > 0, and down if n < 0.
 func (m *Migrate) Steps(n int) error {
+	return m.StepsContext(context.Background(), n)
+}
+
+// StepsContext is Steps, which stops before running the next migration
+// once ctx is done and returns ctx.Err() then. Drivers implementing
+// database.RunnerContext abort the running migration as well.
+func (m *Migrate) StepsContext(ctx context.Context, n int) error {
 	if n == 0 {
-		return ErrNoChange
+		return m.noChangeErr()
 	}
 
-	if err := m.lock(); err != nil {
+	if err := m.checkTransactions(); err != nil {
+		return err
+	}
+
+	ctx, cancel := m.runContext(ctx)
+	defer cancel()
+	if err := m.lockContext(ctx); err != nil {
 		return err
 	}
 
-	curVersion, dirty, err := m.databaseDrv.Version()
+	curVersion, dirty, err := m.databaseVersion()
 	if err != nil {
 		return m.unlockErr(err)
 	}
@@ -251,25 +743,113 @@ func (m *Migrate) Steps(n int) error {
 		return m.unlockErr(ErrDirty{curVersion})
 	}
 
+	if err := m.checkDatabaseVersion(curVersion); err != nil {
+		return m.unlockErr(err)
+	}
+
 	ret := make(chan interface{}, m.PrefetchMigrations)
 
+	total := 0
 	if n > 0 {
 		go m.readUp(curVersion, n, ret)
+		total = m.readUpTotal(curVersion, n)
 	} else {
 		go m.readDown(curVersion, -n, ret)
+		total = m.readDownTotal(curVersion, -n)
+	}
+
+	return m.unlockErr(m.runMigrationsContext(ctx, ret, total))
+}
+
+// ApplyOne applies the next up migration if direction is source.Up, or the
+// down migration of the current version if direction is source.Down, like
+// Steps(1) and Steps(-1). It returns the version of the applied migration,
+// or ErrNoChange if there is no migration to apply. ErrNoChange is returned
+// even if NoChangeIsNil is set, as version 0 can't tell that apart from an
+// applied migration.
+func (m *Migrate) ApplyOne(direction Direction) (version uint, err error) {
+	if direction != source.Up && direction != source.Down {
+		return 0, fmt.Errorf("unknown direction: %q", direction)
+	}
+
+	if err := m.checkTransactions(); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := m.runContext(context.Background())
+	defer cancel()
+	if err := m.lockContext(ctx); err != nil {
+		return 0, err
+	}
+
+	curVersion, dirty, err := m.databaseVersion()
+	if err != nil {
+		return 0, m.unlockErr(err)
+	}
+
+	if dirty {
+		return 0, m.unlockErr(ErrDirty{curVersion})
+	}
+
+	if err := m.checkDatabaseVersion(curVersion); err != nil {
+		return 0, m.unlockErr(err)
 	}
 
-	return m.unlockErr(m.runMigrations(ret))
+	if curVersion >= 0 {
+		if err := m.versionExistsFor(suint(curVersion), direction); err != nil {
+			return 0, m.unlockErr(err)
+		}
+	}
+
+	ret := make(chan interface{}, m.PrefetchMigrations)
+
+	if direction == source.Up {
+		if curVersion == database.NilVersion {
+			version, err = m.first()
+		} else {
+			version, err = m.next(suint(curVersion))
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, m.unlockErr(ErrNoChange)
+		} else if err != nil {
+			return 0, m.unlockErr(err)
+		}
+		go m.readUp(curVersion, 1, ret)
+	} else {
+		if curVersion == database.NilVersion {
+			return 0, m.unlockErr(ErrNoChange)
+		}
+		version = suint(curVersion)
+		go m.readDown(curVersion, 1, ret)
+	}
+
+	if err := m.unlockErr(m.runMigrationsContext(ctx, ret, 1)); err != nil {
+		return 0, err
+	}
+	return version, nil
 }
 
 // Up looks at the currently active migration version
 // and will migrate all the way up (applying all up migrations).
 func (m *Migrate) Up() error {
-	if err := m.lock(); err != nil {
+	return m.UpContext(context.Background())
+}
+
+// UpContext is Up, which stops before running the next migration
+// once ctx is done and returns ctx.Err() then. Drivers implementing
+// database.RunnerContext abort the running migration as well.
+func (m *Migrate) UpContext(ctx context.Context) error {
+	if err := m.checkTransactions(); err != nil {
+		return err
+	}
+
+	ctx, cancel := m.runContext(ctx)
+	defer cancel()
+	if err := m.lockContext(ctx); err != nil {
 		return err
 	}
 
-	curVersion, dirty, err := m.databaseDrv.Version()
+	curVersion, dirty, err := m.databaseVersion()
 	if err != nil {
 		return m.unlockErr(err)
 	}
@@ -278,20 +858,39 @@ func (m *Migrate) Up() error {
 		return m.unlockErr(ErrDirty{curVersion})
 	}
 
+	if err := m.checkDatabaseVersion(curVersion); err != nil {
+		return m.unlockErr(err)
+	}
+
 	ret := make(chan interface{}, m.PrefetchMigrations)
 
 	go m.readUp(curVersion, -1, ret)
-	return m.unlockErr(m.runMigrations(ret))
+	return m.unlockErr(m.runMigrationsContext(ctx, ret, m.readUpTotal(curVersion, -1)))
 }
 
 // Down looks at the currently active migration version
 // and will migrate all the way down (applying all down migrations).
+// Versions without a down migration are stepped over without changing
+// the database, so sources with up migrations only can be migrated down.
 func (m *Migrate) Down() error {
-	if err := m.lock(); err != nil {
+	return m.DownContext(context.Background())
+}
+
+// DownContext is Down, which stops before running the next migration
+// once ctx is done and returns ctx.Err() then. Drivers implementing
+// database.RunnerContext abort the running migration as well.
+func (m *Migrate) DownContext(ctx context.Context) error {
+	if err := m.checkTransactions(); err != nil {
+		return err
+	}
+
+	ctx, cancel := m.runContext(ctx)
+	defer cancel()
+	if err := m.lockContext(ctx); err != nil {
 		return err
 	}
 
-	curVersion, dirty, err := m.databaseDrv.Version()
+	curVersion, dirty, err := m.databaseVersion()
 	if err != nil {
 		return m.unlockErr(err)
 	}
@@ -300,19 +899,193 @@ func (m *Migrate) Down() error {
 		return m.unlockErr(ErrDirty{curVersion})
 	}
 
+	if err := m.checkDatabaseVersion(curVersion); err != nil {
+		return m.unlockErr(err)
+	}
+
 	ret := make(chan interface{}, m.PrefetchMigrations)
 	go m.readDown(curVersion, -1, ret)
-	return m.unlockErr(m.runMigrations(ret))
+	return m.unlockErr(m.runMigrationsContext(ctx, ret, m.readDownTotal(curVersion, -1)))
+}
+
+// PlannedStep is a migration Plan expects to run.
+type PlannedStep struct {
+	// Version is the version of the migration.
+	Version uint
+
+	// TargetVersion is the migration version after the step,
+	// -1 implying NilVersion.
+	TargetVersion int
+
+	// Direction is either source.Up or source.Down.
+	Direction source.Direction
+
+	// Identifier identifies the migration in the source,
+	// "<empty>" if there is no migration file for the step.
+	Identifier string
+}
+
+// Plan returns the migrations Migrate(version) would run, in order, without
+// running them. The migration files are read, but the database is neither
+// locked nor changed.
+func (m *Migrate) Plan(version uint) ([]PlannedStep, error) {
+	_, plan, err := m.plan(version)
+	return plan, err
+}
+
+// plan returns the database version and the migrations Migrate(version)
+// would run from it. The version is also returned along with errors
+// reading the migrations, such as ErrNoChange.
+func (m *Migrate) plan(version uint) (curVersion int, plan []PlannedStep, err error) {
+	curVersion, dirty, err := m.databaseVersion()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if dirty {
+		return 0, nil, ErrDirty{curVersion}
+	}
+
+	if err := m.checkDatabaseVersion(curVersion); err != nil {
+		return 0, nil, err
+	}
+
+	ret := make(chan interface{}, m.PrefetchMigrations)
+	go m.read(curVersion, int(version), ret)
+	defer m.drainMigrations(ret)
+
+	for r := range ret {
+		switch r := r.(type) {
+		case error:
+			return curVersion, nil, r
+
+		case *Migration:
+			if r.Body != nil {
+				// drain the migration, so it's done buffering
+				if _, err := io.Copy(ioutil.Discard, r.BufferedBody); err != nil {
+					return curVersion, nil, err
+				}
+			}
+			plan = append(plan, PlannedStep{
+				Version:       r.Version,
+				TargetVersion: r.TargetVersion,
+				Direction:     r.direction(),
+				Identifier:    r.Identifier,
+			})
+
+		default:
+			return 0, nil, fmt.Errorf("unknown type: %T with value: %+v", r, r)
+		}
+	}
+	return curVersion, plan, nil
 }
 
-// Drop deletes everything in the database.
+// PlanSchemaVersion is the schema version of the documents returned by
+// PlanJSON. It's incremented by changes breaking their consumers, adding
+// fields is not one.
+const PlanSchemaVersion = 1
+
+// PlanDocument is the JSON document returned by PlanJSON.
+type PlanDocument struct {
+	// SchemaVersion is PlanSchemaVersion.
+	SchemaVersion int `json:"schema_version"`
+
+	// CurrentVersion is the database version, -1 implying NilVersion.
+	CurrentVersion int `json:"current_version"`
+
+	// TargetVersion is the version passed to PlanJSON.
+	TargetVersion uint `json:"target_version"`
+
+	// Steps are the migrations to run in order, an empty array if the
+	// database is at TargetVersion already.
+	Steps []PlanDocumentStep `json:"steps"`
+}
+
+// PlanDocumentStep is a PlannedStep of a PlanDocument.
+type PlanDocumentStep struct {
+	Version       uint   `json:"version"`
+	TargetVersion int    `json:"target_version"`
+	Direction     string `json:"direction"`
+	Name          string `json:"name"`
+}
+
+// PlanJSON returns the migrations Migrate(target) would run as PlanDocument,
+// e.g. to post them for approval before applying them. Unlike Plan, it
+// doesn't return ErrNoChange but a document without steps.
+func (m *Migrate) PlanJSON(target uint) ([]byte, error) {
+	curVersion, plan, err := m.plan(target)
+	if err != nil && !errors.Is(err, ErrNoChange) {
+		return nil, err
+	}
+
+	doc := PlanDocument{
+		SchemaVersion:  PlanSchemaVersion,
+		CurrentVersion: curVersion,
+		TargetVersion:  target,
+		Steps:          make([]PlanDocumentStep, 0, len(plan)),
+	}
+	for _, step := range plan {
+		doc.Steps = append(doc.Steps, PlanDocumentStep{
+			Version:       step.Version,
+			TargetVersion: step.TargetVersion,
+			Direction:     string(step.Direction),
+			Name:          step.Identifier,
+		})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Metadata holds information about a migration extracted from its content
+// by a ContentTransformer, e.g. the author or ticket of YAML front matter.
+type Metadata map[string]string
+
+// ContentTransformer returns the content of a migration to be run from
+// its raw content read from the source, e.g. stripping front matter. The
+// returned Metadata is set as Migration.Metadata.
+type ContentTransformer func(raw []byte) (content []byte, metadata Metadata, err error)
+
+// SetContentTransformer sets a function transforming the content of every
+// up and down migration read from the source before it is run by the
+// database driver. Checksums are still computed from the raw content.
+// transformer can be nil.
+func (m *Migrate) SetContentTransformer(transformer ContentTransformer) {
+	m.contentTransformer = transformer
+}
+
+// SetVersionFilter makes Migrate only see the source versions filter
+// returns true for, e.g. the versions of one service of a monorepo. The
+// other versions are neither applied nor counted when stepping through the
+// versions, and going to one of them fails as with a missing version. It
+// must be set before the first migration is run, filter can be nil.
+func (m *Migrate) SetVersionFilter(filter func(version uint) bool) {
+	m.versionFilter = filter
+}
+
+// SetDropConfirm allows Drop to delete everything in the database. Drop
+// fails with ErrDropNotConfirmed until it is called with true, so that it
+// can't be run by accident.
+func (m *Migrate) SetDropConfirm(confirm bool) {
+	m.dropConfirmed = confirm
+}
+
+// Drop deletes everything in the database, and the recorded versions of
+// the VersionStore if one is set. It returns ErrDropNotConfirmed unless
+// allowed with SetDropConfirm.
 func (m *Migrate) Drop() error {
+	if !m.dropConfirmed {
+		return ErrDropNotConfirmed
+	}
 	if err := m.lock(); err != nil {
 		return err
 	}
 	if err := m.databaseDrv.Drop(); err != nil {
 		return m.unlockErr(err)
 	}
+	if m.versionStore != nil {
+		if err := m.versionStore.Drop(); err != nil {
+			return m.unlockErr(err)
+		}
+	}
 	return m.unlock()
 }
 
@@ -322,14 +1095,20 @@ func (m *Migrate) Drop() error {
 // Steps, Up or Down instead.
 func (m *Migrate) Run(migration ...*Migration) error {
 	if len(migration) == 0 {
-		return ErrNoChange
+		return m.noChangeErr()
 	}
 
-	if err := m.lock(); err != nil {
+	if err := m.checkTransactions(); err != nil {
+		return err
+	}
+
+	ctx, cancel := m.runContext(context.Background())
+	defer cancel()
+	if err := m.lockContext(ctx); err != nil {
 		return err
 	}
 
-	curVersion, dirty, err := m.databaseDrv.Version()
+	curVersion, dirty, err := m.databaseVersion()
 	if err != nil {
 		return m.unlockErr(err)
 	}
@@ -358,12 +1137,13 @@ func (m *Migrate) Run(migration ...*Migration) error {
 		}
 	}()
 
-	return m.unlockErr(m.runMigrations(ret))
+	return m.unlockErr(m.runMigrationsContext(ctx, ret, len(migration)))
 }
 
 // Force sets a migration version.
 // It does not check any currently active version in database.
 // It resets the dirty state to false.
+// The version isn't checked against the source either, see ForceChecked.
 func (m *Migrate) Force(version int) error {
 	if version < -1 {
 		return ErrInvalidVersion
@@ -373,18 +1153,220 @@ func (m *Migrate) Force(version int) error {
 		return err
 	}
 
-	if err := m.databaseDrv.SetVersion(version, false); err != nil {
+	if err := m.store().SetVersion(version, false); err != nil {
 		return m.unlockErr(err)
 	}
 
 	return m.unlock()
 }
 
-// Version returns the currently active migration version.
-// If no migration has been applied, yet, it will return ErrNilVersion.
-func (m *Migrate) Version() (version uint, dirty bool, err error) {
-	v, d, err := m.databaseDrv.Version()
-	if err != nil {
+// ForceChecked is Force, verifying that the source has a migration of
+// version first, unless version is NilVersion. Otherwise an error wrapping
+// ErrVersionNotFound is returned and the version is left untouched.
+func (m *Migrate) ForceChecked(version int) error {
+	if version < -1 {
+		return ErrInvalidVersion
+	}
+	if version != database.NilVersion {
+		if err := m.versionExists(uint(version)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+			}
+			return err
+		}
+	}
+	return m.Force(version)
+}
+
+// Baseline records version as applied and clean without running any
+// migration, e.g. to adopt migrate for a database whose schema exists
+// already. The source must have a migration of version, otherwise an error
+// wrapping ErrVersionNotFound is returned. Unlike Force, Baseline refuses to
+// change a database which records a version, dirty or not, returning an
+// error wrapping ErrAlreadyTracked then.
+func (m *Migrate) Baseline(version uint) error {
+	if err := m.versionExists(version); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+		}
+		return err
+	}
+
+	if err := m.lock(); err != nil {
+		return err
+	}
+
+	curVersion, dirty, err := m.databaseVersion()
+	if err != nil {
+		return m.unlockErr(err)
+	}
+	if curVersion != database.NilVersion || dirty {
+		return m.unlockErr(fmt.Errorf("%w: version %d", ErrAlreadyTracked, curVersion))
+	}
+
+	if err := m.store().SetVersion(int(version), false); err != nil {
+		return m.unlockErr(err)
+	}
+
+	return m.unlock()
+}
+
+// Verify compares the checksums recorded for the applied migrations with
+// the checksums of their up migrations in the source. It returns
+// ErrChecksumMismatch listing every applied migration which changed since,
+// and ErrChecksumsNotSupported if the database driver, or the VersionStore
+// if set, doesn't record checksums.
+func (m *Migrate) Verify() error {
+	checksummer, ok := m.store().(database.Checksummer)
+	if !ok {
+		return ErrChecksumsNotSupported
+	}
+
+	curVersion, _, err := m.databaseVersion()
+	if err != nil {
+		return err
+	}
+
+	checksums, err := checksummer.Checksums()
+	if err != nil {
+		return err
+	}
+
+	versions := make([]uint, 0, len(checksums))
+	for version := range checksums {
+		// versions above the current one have been migrated down
+		if !m.before(curVersion, int(version)) {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	var mismatches []ChecksumMismatch
+	for _, version := range versions {
+		checksum, err := m.sourceChecksum(version)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if checksum != checksums[version] {
+			mismatches = append(mismatches, ChecksumMismatch{Version: version, Applied: checksums[version], Source: checksum})
+		}
+	}
+	if len(mismatches) > 0 {
+		return ErrChecksumMismatch{Mismatches: mismatches}
+	}
+	return nil
+}
+
+// sourceChecksum returns the checksum of the up migration of version.
+func (m *Migrate) sourceChecksum(version uint) (checksum string, err error) {
+	r, _, err := m.sourceDrv.ReadUp(version)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if errClose := r.Close(); errClose != nil {
+			err = multierror.Append(err, errClose)
+		}
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Pending returns the versions in the source above the currently active
+// migration version in ascending order, i.e. the versions Up would apply.
+// If the database is dirty, the versions above the dirty version are listed
+// and dirty is true.
+func (m *Migrate) Pending() (versions []uint, dirty bool, err error) {
+	if err := m.lockRead(); err != nil {
+		return nil, false, err
+	}
+
+	curVersion, dirty, err := m.databaseVersion()
+	if err != nil {
+		return nil, false, m.unlockReadErr(err)
+	}
+
+	versions, _, err = m.pendingVersions(curVersion)
+	if err != nil {
+		return nil, false, m.unlockReadErr(err)
+	}
+	return versions, dirty, m.unlockRead()
+}
+
+// pendingVersions returns the versions in the source above curVersion and
+// the number of versions in the source.
+func (m *Migrate) pendingVersions(curVersion int) (versions []uint, total int, err error) {
+	versions = []uint{}
+	version, err := m.first()
+	for err == nil {
+		total++
+		if m.before(curVersion, int(version)) {
+			versions = append(versions, version)
+		}
+		version, err = m.next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, 0, err
+	}
+	return versions, total, nil
+}
+
+// Status is a snapshot of the state of the migrations, see Migrate.Status.
+type Status struct {
+	// CurrentVersion is the active migration version of the database,
+	// database.NilVersion if no migration has been applied yet.
+	CurrentVersion int
+	Dirty          bool
+	// Pending are the versions in the source above CurrentVersion, as
+	// listed by Pending.
+	Pending []uint
+	// TotalAvailable is the number of versions in the source.
+	TotalAvailable int
+}
+
+// Status returns the version of the database together with the pending
+// and available versions of the source. The migration lock is only taken
+// if LockReads is set.
+func (m *Migrate) Status() (Status, error) {
+	if err := m.lockRead(); err != nil {
+		return Status{}, err
+	}
+
+	curVersion, dirty, err := m.databaseVersion()
+	if err != nil {
+		return Status{}, m.unlockReadErr(err)
+	}
+	pending, total, err := m.pendingVersions(curVersion)
+	if err != nil {
+		return Status{}, m.unlockReadErr(err)
+	}
+
+	status := Status{
+		CurrentVersion: curVersion,
+		Dirty:          dirty,
+		Pending:        pending,
+		TotalAvailable: total,
+	}
+	return status, m.unlockRead()
+}
+
+// Version returns the currently active migration version.
+// If no migration has been applied, yet, it will return ErrNilVersion.
+// The migration lock is only taken if LockReads is set.
+func (m *Migrate) Version() (version uint, dirty bool, err error) {
+	if err := m.lockRead(); err != nil {
+		return 0, false, err
+	}
+
+	v, d, err := m.databaseVersion()
+	if err != nil {
+		return 0, false, m.unlockReadErr(err)
+	}
+	if err := m.unlockRead(); err != nil {
 		return 0, false, err
 	}
 
@@ -401,79 +1383,64 @@ func (m *Migrate) Version() (version uint, dirty bool, err error) {
 // Once read is done reading it will close the ret channel.
 func (m *Migrate) read(from int, to int, ret chan<- interface{}) {
 	defer close(ret)
+	p := m.newPrefetcher(ret)
+	defer p.close()
+
+	direction := source.Up
+	if m.before(to, from) {
+		direction = source.Down
+	}
 
 	// check if from version exists
 	if from >= 0 {
-		if err := m.versionExists(suint(from)); err != nil {
-			ret <- err
+		if err := m.versionExistsFor(suint(from), direction); err != nil {
+			p.err(err)
 			return
 		}
 	}
 
 	// check if to version exists
 	if to >= 0 {
-		if err := m.versionExists(suint(to)); err != nil {
-			ret <- err
+		if err := m.versionExistsFor(suint(to), direction); err != nil {
+			p.err(err)
 			return
 		}
 	}
 
 	// no change?
 	if from == to {
-		ret <- ErrNoChange
+		p.err(ErrNoChange)
 		return
 	}
 
-	if from < to {
+	if m.before(from, to) {
 		// it's going up
 		// apply first migration if from is nil version
 		if from == -1 {
-			firstVersion, err := m.sourceDrv.First()
+			firstVersion, err := m.first()
 			if err != nil {
-				ret <- err
+				p.err(err)
 				return
 			}
 
-			migr, err := m.newMigration(firstVersion, int(firstVersion))
-			if err != nil {
-				ret <- err
-				return
-			}
-
-			ret <- migr
-			go func() {
-				if err := migr.Buffer(); err != nil {
-					m.logErr(err)
-				}
-			}()
+			p.migration(firstVersion, int(firstVersion))
 
 			from = int(firstVersion)
 		}
 
 		// run until we reach target ...
-		for from < to {
+		for m.before(from, to) {
 			if m.stop() {
 				return
 			}
 
-			next, err := m.sourceDrv.Next(suint(from))
+			next, err := m.next(suint(from))
 			if err != nil {
-				ret <- err
+				p.err(err)
 				return
 			}
 
-			migr, err := m.newMigration(next, int(next))
-			if err != nil {
-				ret <- err
-				return
-			}
-
-			ret <- migr
-			go func() {
-				if err := migr.Buffer(); err != nil {
-					m.logErr(err)
-				}
-			}()
+			p.migration(next, int(next))
 
 			from = int(next)
 		}
@@ -481,45 +1448,23 @@ func (m *Migrate) read(from int, to int, ret chan<- interface{}) {
 	} else {
 		// it's going down
 		// run until we reach target ...
-		for from > to && from >= 0 {
+		for m.before(to, from) && from >= 0 {
 			if m.stop() {
 				return
 			}
 
-			prev, err := m.sourceDrv.Prev(suint(from))
+			prev, err := m.prev(suint(from))
 			if errors.Is(err, os.ErrNotExist) && to == -1 {
 				// apply nil migration
-				migr, err := m.newMigration(suint(from), -1)
-				if err != nil {
-					ret <- err
-					return
-				}
-				ret <- migr
-				go func() {
-					if err := migr.Buffer(); err != nil {
-						m.logErr(err)
-					}
-				}()
-
+				p.migration(suint(from), -1)
 				return
 
 			} else if err != nil {
-				ret <- err
+				p.err(err)
 				return
 			}
 
-			migr, err := m.newMigration(suint(from), int(prev))
-			if err != nil {
-				ret <- err
-				return
-			}
-
-			ret <- migr
-			go func() {
-				if err := migr.Buffer(); err != nil {
-					m.logErr(err)
-				}
-			}()
+			p.migration(suint(from), int(prev))
 
 			from = int(prev)
 		}
@@ -533,17 +1478,19 @@ func (m *Migrate) read(from int, to int, ret chan<- interface{}) {
 // Once readUp is done reading it will close the ret channel.
 func (m *Migrate) readUp(from int, limit int, ret chan<- interface{}) {
 	defer close(ret)
+	p := m.newPrefetcher(ret)
+	defer p.close()
 
 	// check if from version exists
 	if from >= 0 {
-		if err := m.versionExists(suint(from)); err != nil {
-			ret <- err
+		if err := m.versionExistsFor(suint(from), source.Up); err != nil {
+			p.err(err)
 			return
 		}
 	}
 
 	if limit == 0 {
-		ret <- ErrNoChange
+		p.err(ErrNoChange)
 		return
 	}
 
@@ -555,35 +1502,24 @@ func (m *Migrate) readUp(from int, limit int, ret chan<- interface{}) {
 
 		// apply first migration if from is nil version
 		if from == -1 {
-			firstVersion, err := m.sourceDrv.First()
+			firstVersion, err := m.first()
 			if err != nil {
-				ret <- err
+				p.err(err)
 				return
 			}
 
-			migr, err := m.newMigration(firstVersion, int(firstVersion))
-			if err != nil {
-				ret <- err
-				return
-			}
-
-			ret <- migr
-			go func() {
-				if err := migr.Buffer(); err != nil {
-					m.logErr(err)
-				}
-			}()
+			p.migration(firstVersion, int(firstVersion))
 			from = int(firstVersion)
 			count++
 			continue
 		}
 
 		// apply next migration
-		next, err := m.sourceDrv.Next(suint(from))
+		next, err := m.next(suint(from))
 		if errors.Is(err, os.ErrNotExist) {
 			// no limit, but no migrations applied?
 			if limit == -1 && count == 0 {
-				ret <- ErrNoChange
+				p.err(ErrNoChange)
 				return
 			}
 
@@ -594,33 +1530,22 @@ func (m *Migrate) readUp(from int, limit int, ret chan<- interface{}) {
 
 			// reached end, and didn't apply any migrations
 			if limit > 0 && count == 0 {
-				ret <- os.ErrNotExist
+				p.err(os.ErrNotExist)
 				return
 			}
 
 			// applied less migrations than limit?
 			if count < limit {
-				ret <- ErrShortLimit{suint(limit - count)}
+				p.err(ErrShortLimit{suint(limit - count)})
 				return
 			}
 		}
 		if err != nil {
-			ret <- err
-			return
-		}
-
-		migr, err := m.newMigration(next, int(next))
-		if err != nil {
-			ret <- err
+			p.err(err)
 			return
 		}
 
-		ret <- migr
-		go func() {
-			if err := migr.Buffer(); err != nil {
-				m.logErr(err)
-			}
-		}()
+		p.migration(next, int(next))
 		from = int(next)
 		count++
 	}
@@ -633,29 +1558,31 @@ func (m *Migrate) readUp(from int, limit int, ret chan<- interface{}) {
 // Once readDown is done reading it will close the ret channel.
 func (m *Migrate) readDown(from int, limit int, ret chan<- interface{}) {
 	defer close(ret)
+	p := m.newPrefetcher(ret)
+	defer p.close()
 
 	// check if from version exists
 	if from >= 0 {
-		if err := m.versionExists(suint(from)); err != nil {
-			ret <- err
+		if err := m.versionExistsFor(suint(from), source.Down); err != nil {
+			p.err(err)
 			return
 		}
 	}
 
 	if limit == 0 {
-		ret <- ErrNoChange
+		p.err(ErrNoChange)
 		return
 	}
 
 	// no change if already at nil version
 	if from == -1 && limit == -1 {
-		ret <- ErrNoChange
+		p.err(ErrNoChange)
 		return
 	}
 
 	// can't go over limit if already at nil version
 	if from == -1 && limit > 0 {
-		ret <- os.ErrNotExist
+		p.err(os.ErrNotExist)
 		return
 	}
 
@@ -665,146 +1592,542 @@ func (m *Migrate) readDown(from int, limit int, ret chan<- interface{}) {
 			return
 		}
 
-		prev, err := m.sourceDrv.Prev(suint(from))
+		prev, err := m.prev(suint(from))
 		if errors.Is(err, os.ErrNotExist) {
 			// no limit or haven't reached limit, apply "first" migration
 			if limit == -1 || limit-count > 0 {
-				firstVersion, err := m.sourceDrv.First()
+				firstVersion, err := m.first()
 				if err != nil {
-					ret <- err
+					p.err(err)
 					return
 				}
 
-				migr, err := m.newMigration(firstVersion, -1)
-				if err != nil {
-					ret <- err
-					return
-				}
-				ret <- migr
-				go func() {
-					if err := migr.Buffer(); err != nil {
-						m.logErr(err)
-					}
-				}()
+				p.migration(firstVersion, -1)
 				count++
 			}
 
 			if count < limit {
-				ret <- ErrShortLimit{suint(limit - count)}
+				p.err(ErrShortLimit{suint(limit - count)})
 			}
 			return
 		}
 		if err != nil {
-			ret <- err
+			p.err(err)
 			return
 		}
 
-		migr, err := m.newMigration(suint(from), int(prev))
+		p.migration(suint(from), int(prev))
+		from = int(prev)
+		count++
+	}
+}
+
+// prefetcher opens up to PrefetchMigrations migrations concurrently,
+// while sending them and any error on ret in the order they were scheduled.
+type prefetcher struct {
+	m   *Migrate
+	ret chan<- interface{}
+
+	// sem bounds the number of migrations opened concurrently
+	sem chan struct{}
+
+	// queue holds a channel per scheduled migration or error, which
+	// receives the opened migration or the error
+	queue chan chan interface{}
+	done  chan struct{}
+}
+
+func (m *Migrate) newPrefetcher(ret chan<- interface{}) *prefetcher {
+	n := m.PrefetchMigrations
+	if n == 0 {
+		n = 1
+	}
+	p := &prefetcher{
+		m:     m,
+		ret:   ret,
+		sem:   make(chan struct{}, n),
+		queue: make(chan chan interface{}, n),
+		done:  make(chan struct{}),
+	}
+	go p.forward()
+	return p
+}
+
+// migration schedules the *Migration for the specified version and targetVersion.
+func (p *prefetcher) migration(version uint, targetVersion int) {
+	result := make(chan interface{}, 1)
+	p.sem <- struct{}{}
+	p.queue <- result
+	go func() {
+		defer func() {
+			<-p.sem
+		}()
+		migr, err := p.m.newMigration(version, targetVersion)
 		if err != nil {
-			ret <- err
+			result <- err
 			return
 		}
+		result <- migr
+	}()
+}
 
-		ret <- migr
-		go func() {
-			if err := migr.Buffer(); err != nil {
-				m.logErr(err)
+// err schedules an error after the migrations scheduled so far.
+func (p *prefetcher) err(err error) {
+	result := make(chan interface{}, 1)
+	result <- err
+	p.queue <- result
+}
+
+// forward sends the scheduled migrations and errors on ret, once they are
+// available, and starts buffering the migrations. Nothing is sent after
+// the first error.
+func (p *prefetcher) forward() {
+	defer close(p.done)
+	failed := false
+	for result := range p.queue {
+		r := <-result
+		if failed {
+			if migr, ok := r.(*Migration); ok && migr.Body != nil {
+				if err := migr.Body.Close(); err != nil {
+					p.m.logErr(err)
+				}
 			}
-		}()
-		from = int(prev)
-		count++
+			continue
+		}
+
+		p.ret <- r
+		switch r := r.(type) {
+		case error:
+			failed = true
+		case *Migration:
+			go func() {
+				if err := r.Buffer(); err != nil {
+					p.m.logErr(err)
+				}
+			}()
+		}
 	}
 }
 
-// runMigrations reads *Migration and error from a channel. Any other type
+// close waits until everything scheduled has been sent on ret.
+func (p *prefetcher) close() {
+	close(p.queue)
+	<-p.done
+}
+
+// checkTransactions returns ErrTransactionsNotSupported if
+// WholeRunInTransaction is set but the database driver doesn't implement
+// database.Transactioner. It's checked before locking and reading any
+// migration, so that a rejected run leaves nothing behind.
+func (m *Migrate) checkTransactions() error {
+	if !m.WholeRunInTransaction {
+		return nil
+	}
+	if _, ok := m.databaseDrv.(database.Transactioner); !ok {
+		return ErrTransactionsNotSupported
+	}
+	return nil
+}
+
+// runMigrationsContext reads *Migration and error from a channel. Any other type
 // sent on this channel will result in a panic. Each migration is then
 // proxied to the database driver and run against the database.
 // Before running a newly received migration it will check if it's supposed
 // to stop execution because it might have received a stop signal on the
-// GracefulStop channel.
-func (m *Migrate) runMigrations(ret <-chan interface{}) error {
+// GracefulStop channel. Once ctx is done it returns ctx.Err() instead of
+// running the next migration. On every return the migrations left on ret
+// are drained, so that the reader finishes. total is the number of
+// migrations expected on ret, reported as Progress.Total.
+func (m *Migrate) runMigrationsContext(ctx context.Context, ret <-chan interface{}, total int) (err error) {
+	defer m.drainMigrations(ret)
+
+	if setter, ok := m.databaseDrv.(LoggerSetter); ok && m.Log != nil {
+		setter.SetLogger(m.Log)
+	}
+
+	if m.WholeRunInTransaction {
+		txDrv, ok := m.databaseDrv.(database.Transactioner)
+		if !ok {
+			return ErrTransactionsNotSupported
+		}
+		if err := txDrv.Begin(); err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = txDrv.Commit()
+			} else if errRollback := txDrv.Rollback(); errRollback != nil {
+				err = multierror.Append(err, errRollback)
+			}
+		}()
+	}
+
+	progress := m.progress
+	m.progress = nil
+	if progress != nil {
+		defer close(progress)
+	}
+
+	m.stopped = false
+	// applied is the target version of the last migration run, if any
+	applied, ran := 0, false
+	defer func() {
+		// the reader may have stopped first, closing ret
+		if err == nil && m.isStopped() {
+			err = m.recordStop(applied, ran)
+		}
+	}()
+
+	current := 0
 	for r := range ret {
 
 		if m.stop() {
+			m.drainMigration(r)
 			return nil
 		}
 
+		if err := ctx.Err(); err != nil {
+			m.drainMigration(r)
+			return err
+		}
+
 		switch r := r.(type) {
 		case error:
+			if r == ErrNoChange {
+				return m.noChangeErr()
+			}
 			return r
 
 		case *Migration:
-			migr := r
+			current++
+			if progress != nil {
+				select {
+				case progress <- Progress{Current: current, Total: total, Version: r.Version, Direction: r.direction()}:
+				default:
+				}
+			}
 
-			// set version with dirty state
-			if err := m.databaseDrv.SetVersion(migr.TargetVersion, true); err != nil {
+			start := m.now()
+			err := m.runMigration(ctx, r)
+			if m.metricsSink != nil {
+				m.metricsSink(r.Version, r.direction(), m.now().Sub(start), err)
+			}
+			if err != nil {
+				// the driver may have stopped reading the body
+				m.drainMigration(r)
 				return err
 			}
+			applied, ran = r.TargetVersion, true
 
-			if migr.Body != nil {
-				m.logVerbosePrintf("Read and execute %v\n", migr.LogString())
-				if err := m.databaseDrv.Run(migr.BufferedBody); err != nil {
-					return err
-				}
+		default:
+			return fmt.Errorf("unknown type: %T with value: %+v", r, r)
+		}
+	}
+	return nil
+}
+
+// drainMigrations receives the migrations left on ret after an early return,
+// so that the reader doesn't block forever sending them, and drains their
+// bodies, so that they're done buffering and closed. It returns once the
+// reader closed ret.
+func (m *Migrate) drainMigrations(ret <-chan interface{}) {
+	for r := range ret {
+		m.drainMigration(r)
+	}
+}
+
+// drainMigration drains the body of r if it's a *Migration which isn't run.
+func (m *Migrate) drainMigration(r interface{}) {
+	if r, ok := r.(*Migration); ok && r.Body != nil {
+		if _, err := io.Copy(ioutil.Discard, r.BufferedBody); err != nil {
+			m.logErr(err)
+		}
+	}
+}
+
+// recordStop records the database version of a run stopped through
+// GracefulStop, which is applied if a migration was run.
+func (m *Migrate) recordStop(applied int, ran bool) error {
+	if !ran {
+		version, _, err := m.databaseVersion()
+		if err != nil {
+			return err
+		}
+		applied = version
+	}
+	m.stopped = true
+	m.stoppedAt = applied
+	return nil
+}
+
+// StoppedAt returns the database version, i.e. the last version fully
+// applied, when the last run was stopped through GracefulStop, so that a
+// deployment can be resumed from there. ok is false if the last run wasn't
+// stopped or the database had no version yet.
+func (m *Migrate) StoppedAt() (version uint, ok bool) {
+	if !m.stopped || m.stoppedAt < 0 {
+		return 0, false
+	}
+	return uint(m.stoppedAt), true
+}
+
+// transform returns the content of migr transformed by the content
+// transformer, setting migr.Metadata.
+func (m *Migrate) transform(migr *Migration, body io.Reader) (io.Reader, error) {
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	content, metadata, err := m.contentTransformer(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to transform %v: %w", migr.LogString(), err)
+	}
+	migr.Metadata = metadata
+	return bytes.NewReader(content), nil
+}
+
+// readTotal returns the number of migrations read by read from from to to,
+// counted from the ordered versions without opening any migration. It's 0
+// if there is no Progress channel to report it on, or if read will fail.
+func (m *Migrate) readTotal(from int, to int) int {
+	i, j, _, ok := m.versionIndexes(from, to)
+	if !ok {
+		return 0
+	}
+	if j < i {
+		return i - j
+	}
+	return j - i
+}
+
+// readUpTotal returns the number of migrations read by readUp, like
+// readTotal.
+func (m *Migrate) readUpTotal(from int, limit int) int {
+	i, _, n, ok := m.versionIndexes(from, from)
+	if !ok {
+		return 0
+	}
+	return limitTotal(n-1-i, limit)
+}
+
+// readDownTotal returns the number of migrations read by readDown, like
+// readTotal.
+func (m *Migrate) readDownTotal(from int, limit int) int {
+	i, _, _, ok := m.versionIndexes(from, from)
+	if !ok {
+		return 0
+	}
+	return limitTotal(i+1, limit)
+}
+
+// versionIndexes returns the indexes of from and to in the ordered
+// versions, -1 for NilVersion, and the number of versions. ok is false if
+// there is no Progress channel or a version isn't found.
+func (m *Migrate) versionIndexes(from int, to int) (i int, j int, n int, ok bool) {
+	if m.progress == nil {
+		return 0, 0, 0, false
+	}
+	versions, err := m.orderedVersions()
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	index := func(version int) int {
+		for k, v := range versions {
+			if version >= 0 && v == uint(version) {
+				return k
 			}
+		}
+		return -1
+	}
+	i, j = index(from), index(to)
+	if (from >= 0 && i < 0) || (to >= 0 && j < 0) {
+		return 0, 0, 0, false
+	}
+	return i, j, len(versions), true
+}
+
+// limitTotal returns available capped at limit, unless limit is -1.
+func limitTotal(available int, limit int) int {
+	if limit >= 0 && limit < available {
+		return limit
+	}
+	return available
+}
+
+// runMigration runs a single migration against the database. Migrations
+// whose body is a source.Executor are executed by the source instead.
+func (m *Migrate) runMigration(ctx context.Context, migr *Migration) error {
+	if migr.Body != nil && m.beforeHook != nil {
+		if err := m.beforeHook(migr.Version, migr.direction()); err != nil {
+			return err
+		}
+	}
 
-			// set clean state
-			if err := m.databaseDrv.SetVersion(migr.TargetVersion, false); err != nil {
+	// set version with dirty state
+	if err := m.store().SetVersion(migr.TargetVersion, true); err != nil {
+		return err
+	}
+
+	if migr.Body != nil {
+		if l, ok := m.leveledLogger(); ok {
+			l.Info("applying version", "version", migr.Version, "direction", migr.direction(), "identifier", migr.Identifier)
+		} else {
+			m.logVerbosePrintf("Read and execute %v\n", migr.LogString())
+		}
+		if executor, ok := migr.Body.(source.Executor); ok {
+			if err := m.execute(ctx, migr, executor); err != nil {
 				return err
 			}
+		} else if err := m.runBody(ctx, migr); err != nil {
+			return err
+		}
 
-			endTime := time.Now()
-			readTime := migr.FinishedReading.Sub(migr.StartedBuffering)
-			runTime := endTime.Sub(migr.FinishedReading)
-
-			// log either verbose or normal
-			if m.Log != nil {
-				if m.Log.Verbose() {
-					m.logPrintf("Finished %v (read %v, ran %v)\n", migr.LogString(), readTime, runTime)
-				} else {
-					m.logPrintf("%v (%v)\n", migr.LogString(), readTime+runTime)
-				}
+		if m.afterHook != nil {
+			if err := m.afterHook(migr.Version, migr.direction()); err != nil {
+				return err
 			}
+		}
+	}
 
-		default:
-			return fmt.Errorf("unknown type: %T with value: %+v", r, r)
+	// set clean state
+	if err := m.store().SetVersion(migr.TargetVersion, false); err != nil {
+		return err
+	}
+
+	endTime := m.now()
+	readTime := migr.FinishedReading.Sub(migr.StartedBuffering)
+	runTime := endTime.Sub(migr.FinishedReading)
+
+	// log either leveled, verbose or normal
+	if l, ok := m.leveledLogger(); ok {
+		l.Info("applied version", "version", migr.Version, "direction", migr.direction(), "identifier", migr.Identifier,
+			"read", readTime, "ran", runTime, "duration", readTime+runTime)
+	} else if m.Log != nil {
+		if m.Log.Verbose() {
+			m.logPrintf("Finished %v (read %v, ran %v)\n", migr.LogString(), readTime, runTime)
+		} else {
+			m.logPrintf("%v (%v)\n", migr.LogString(), readTime+runTime)
 		}
 	}
+
 	return nil
 }
 
-// versionExists checks the source if either the up or down migration for
-// the specified migration version exists.
-func (m *Migrate) versionExists(version uint) (result error) {
-	// try up migration first
-	up, _, err := m.sourceDrv.ReadUp(version)
-	if err == nil {
-		defer func() {
-			if errClose := up.Close(); errClose != nil {
-				result = multierror.Append(result, errClose)
-			}
-		}()
+// runBody runs the body of migr with the database driver, recording its
+// checksum if supported. ctx aborts the migration if the driver implements
+// database.RunnerContext.
+func (m *Migrate) runBody(ctx context.Context, migr *Migration) error {
+	body := migr.BufferedBody
+	checksummer, recordChecksum := m.store().(database.Checksummer)
+	recordChecksum = recordChecksum && migr.direction() == source.Up
+	hash := sha256.New()
+	if recordChecksum {
+		body = io.TeeReader(body, hash)
 	}
-	if errors.Is(err, os.ErrExist) {
-		return nil
-	} else if !errors.Is(err, os.ErrNotExist) {
+	var err error
+	if m.contentTransformer != nil {
+		if body, err = m.transform(migr, body); err != nil {
+			return err
+		}
+	}
+	if runner, ok := m.databaseDrv.(database.RunnerContext); ok {
+		err = runner.RunContext(ctx, body)
+	} else {
+		err = m.databaseDrv.Run(body)
+	}
+	if err != nil {
+		return ErrMigrationFailed{Version: migr.Version, Direction: migr.direction(), Identifier: migr.Identifier, Err: err}
+	}
+	if recordChecksum {
+		// hash what the driver didn't read
+		if _, err := io.Copy(ioutil.Discard, body); err != nil {
+			return err
+		}
+		if err := checksummer.SetChecksum(migr.Version, hex.EncodeToString(hash.Sum(nil))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute runs migr with the executor of the source. Its body holds no
+// statements and is only drained for Buffer.
+func (m *Migrate) execute(ctx context.Context, migr *Migration, executor source.Executor) error {
+	if _, err := io.Copy(ioutil.Discard, migr.BufferedBody); err != nil {
 		return err
 	}
+	if err := executor.Execute(ctx); err != nil {
+		return ErrMigrationFailed{Version: migr.Version, Direction: migr.direction(), Identifier: migr.Identifier, Err: err}
+	}
+	return nil
+}
 
-	// then try down migration
-	down, _, err := m.sourceDrv.ReadDown(version)
-	if err == nil {
-		defer func() {
-			if errClose := down.Close(); errClose != nil {
-				result = multierror.Append(result, errClose)
-			}
-		}()
+// noChangeErr returns ErrNoChange unless NoChangeIsNil is set.
+func (m *Migrate) noChangeErr() error {
+	if m.NoChangeIsNil {
+		return nil
 	}
-	if errors.Is(err, os.ErrExist) {
+	return ErrNoChange
+}
+
+// checkDatabaseVersion returns ErrDatabaseAheadOfSource if curVersion
+// isn't in the source and after its latest version. Empty sources and
+// versions missing between two source versions are left to the caller.
+func (m *Migrate) checkDatabaseVersion(curVersion int) error {
+	if curVersion == database.NilVersion {
 		return nil
-	} else if !errors.Is(err, os.ErrNotExist) {
+	}
+	versions, err := m.orderedVersions()
+	if err != nil || len(versions) == 0 {
+		return err
+	}
+	for _, version := range versions {
+		if version == suint(curVersion) {
+			return nil
+		}
+	}
+	latest := versions[len(versions)-1]
+	if m.before(int(latest), curVersion) {
+		err := ErrDatabaseAheadOfSource{Version: curVersion, Latest: latest}
+		m.logErr(err)
 		return err
 	}
+	return nil
+}
+
+// versionExists checks the source if either the up or down migration for
+// the specified migration version exists.
+func (m *Migrate) versionExists(version uint) error {
+	return m.versionExistsFor(version, source.Up)
+}
+
+// versionExistsFor is versionExists, which reads the migration of direction
+// first, so that the other one is only read if it doesn't exist. Runs in one
+// direction don't read the files of the other direction then.
+func (m *Migrate) versionExistsFor(version uint, direction source.Direction) error {
+	if m.versionFilter != nil && !m.versionFilter(version) {
+		err := fmt.Errorf("no migration found for version %d: version is filtered: %w", version, os.ErrNotExist)
+		m.logErr(err)
+		return err
+	}
+
+	reads := []func(uint) (io.ReadCloser, string, error){m.sourceDrv.ReadUp, m.sourceDrv.ReadDown}
+	if direction == source.Down {
+		reads[0], reads[1] = reads[1], reads[0]
+	}
+	var err error
+	for _, read := range reads {
+		var r io.ReadCloser
+		r, _, err = read(version)
+		if err == nil {
+			return r.Close()
+		}
+		if errors.Is(err, os.ErrExist) {
+			return nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
 
 	err = fmt.Errorf("no migration found for version %d: %w", version, err)
 	m.logErr(err)
@@ -815,6 +2138,8 @@ func (m *Migrate) versionExists(version uint) (result error) {
 // because a stop signal was received on the GracefulStop channel.
 // Calls are cheap and this function is not blocking.
 func (m *Migrate) stop() bool {
+	m.isGracefulStopMu.Lock()
+	defer m.isGracefulStopMu.Unlock()
 	if m.isGracefulStop {
 		return true
 	}
@@ -829,16 +2154,23 @@ func (m *Migrate) stop() bool {
 	}
 }
 
+// isStopped returns true if a stop signal was received by stop.
+func (m *Migrate) isStopped() bool {
+	m.isGracefulStopMu.Lock()
+	defer m.isGracefulStopMu.Unlock()
+	return m.isGracefulStop
+}
+
 // newMigration is a helper func that returns a *Migration for the
 // specified version and targetVersion.
 func (m *Migrate) newMigration(version uint, targetVersion int) (*Migration, error) {
 	var migr *Migration
 
-	if targetVersion >= int(version) {
+	if !m.before(targetVersion, int(version)) {
 		r, identifier, err := m.sourceDrv.ReadUp(version)
 		if errors.Is(err, os.ErrNotExist) {
 			// create "empty" migration
-			migr, err = NewMigration(nil, "", version, targetVersion)
+			migr, err = newMigration(nil, "", version, targetVersion, m.now)
 			if err != nil {
 				return nil, err
 			}
@@ -848,7 +2180,7 @@ func (m *Migrate) newMigration(version uint, targetVersion int) (*Migration, err
 
 		} else {
 			// create migration from up source
-			migr, err = NewMigration(r, identifier, version, targetVersion)
+			migr, err = newMigration(r, identifier, version, targetVersion, m.now)
 			if err != nil {
 				return nil, err
 			}
@@ -858,7 +2190,7 @@ func (m *Migrate) newMigration(version uint, targetVersion int) (*Migration, err
 		r, identifier, err := m.sourceDrv.ReadDown(version)
 		if errors.Is(err, os.ErrNotExist) {
 			// create "empty" migration
-			migr, err = NewMigration(nil, "", version, targetVersion)
+			migr, err = newMigration(nil, "", version, targetVersion, m.now)
 			if err != nil {
 				return nil, err
 			}
@@ -868,13 +2200,18 @@ func (m *Migrate) newMigration(version uint, targetVersion int) (*Migration, err
 
 		} else {
 			// create migration from down source
-			migr, err = NewMigration(r, identifier, version, targetVersion)
+			migr, err = newMigration(r, identifier, version, targetVersion, m.now)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
 
+	migr.dir = source.Up
+	if m.before(targetVersion, int(version)) {
+		migr.dir = source.Down
+	}
+
 	if m.PrefetchMigrations > 0 && migr.Body != nil {
 		m.logVerbosePrintf("Start buffering %v\n", migr.LogString())
 	} else {
@@ -884,9 +2221,135 @@ func (m *Migrate) newMigration(version uint, targetVersion int) (*Migration, err
 	return migr, nil
 }
 
+// before reports whether version a sorts before version b, using
+// VersionComparator if set. NilVersion sorts before any other version.
+func (m *Migrate) before(a, b int) bool {
+	if m.VersionComparator == nil || a < 0 || b < 0 {
+		return a < b
+	}
+	return m.VersionComparator(uint(a), uint(b))
+}
+
+// orderedVersions returns the versions of the source selected by the
+// version filter, sorted by VersionComparator. The source is only walked
+// once, with a single call if it implements source.Lister.
+func (m *Migrate) orderedVersions() ([]uint, error) {
+	m.versionsOnce.Do(func() {
+		all, err := m.sourceVersions()
+		if err != nil {
+			m.versionsErr = err
+			return
+		}
+		var versions []uint
+		for _, version := range all {
+			if m.versionFilter == nil || m.versionFilter(version) {
+				versions = append(versions, version)
+			}
+		}
+		if m.VersionComparator != nil {
+			sort.SliceStable(versions, func(i, j int) bool {
+				return m.VersionComparator(versions[i], versions[j])
+			})
+		}
+		m.versions = versions
+	})
+	return m.versions, m.versionsErr
+}
+
+// sourceVersions returns all versions of the source in ascending order.
+func (m *Migrate) sourceVersions() ([]uint, error) {
+	return listVersions(m.sourceDrv)
+}
+
+// listVersions returns all versions of d in ascending order, with a single
+// call if it implements source.Lister.
+func listVersions(d source.Driver) ([]uint, error) {
+	if lister, ok := d.(source.Lister); ok {
+		return lister.Versions()
+	}
+	var versions []uint
+	version, err := d.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = d.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// sourceOrder reports whether the versions are stepped through with First,
+// Next and Prev of the source, i.e. there is neither a VersionComparator
+// nor a filter and the source doesn't implement source.Lister.
+func (m *Migrate) sourceOrder() bool {
+	if _, ok := m.sourceDrv.(source.Lister); ok {
+		return false
+	}
+	return m.VersionComparator == nil && m.versionFilter == nil
+}
+
+// first returns the first version of the source in VersionComparator order.
+func (m *Migrate) first() (uint, error) {
+	if m.sourceOrder() {
+		return m.sourceDrv.First()
+	}
+	versions, err := m.orderedVersions()
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, &os.PathError{Op: "first", Path: m.sourceName, Err: os.ErrNotExist}
+	}
+	return versions[0], nil
+}
+
+// next returns the version following version in VersionComparator order.
+func (m *Migrate) next(version uint) (uint, error) {
+	if m.sourceOrder() {
+		return m.sourceDrv.Next(version)
+	}
+	return m.adjacentVersion(version, 1, "next")
+}
+
+// prev returns the version preceding version in VersionComparator order.
+func (m *Migrate) prev(version uint) (uint, error) {
+	if m.sourceOrder() {
+		return m.sourceDrv.Prev(version)
+	}
+	return m.adjacentVersion(version, -1, "prev")
+}
+
+// adjacentVersion returns the version offset positions away from version
+// in VersionComparator order.
+func (m *Migrate) adjacentVersion(version uint, offset int, op string) (uint, error) {
+	versions, err := m.orderedVersions()
+	if err != nil {
+		return 0, err
+	}
+	for i, v := range versions {
+		if v == version {
+			if j := i + offset; j >= 0 && j < len(versions) {
+				return versions[j], nil
+			}
+			break
+		}
+	}
+	return 0, &os.PathError{
+		Op:   op + " for version " + strconv.FormatUint(uint64(version), 10),
+		Path: m.sourceName,
+		Err:  os.ErrNotExist,
+	}
+}
+
 // lock is a thread safe helper function to lock the database.
 // It should be called as late as possible when running migrations.
 func (m *Migrate) lock() error {
+	return m.lockContext(context.Background())
+}
+
+// lockContext is lock, giving up once ctx is done.
+func (m *Migrate) lockContext(parent context.Context) error {
 	m.isLockedMu.Lock()
 	defer m.isLockedMu.Unlock()
 
@@ -894,6 +2357,53 @@ func (m *Migrate) lock() error {
 		return ErrLocked
 	}
 
+	if err := m.claimDriver(); err != nil {
+		return err
+	}
+	err := m.retry(parent, func() error {
+		return m.lockOnce(parent)
+	})
+	if err != nil {
+		m.releaseDriver()
+	}
+	return err
+}
+
+// claimDriver registers m as the instance locking its database driver,
+// failing with ErrDriverInUse if another instance locked it.
+func (m *Migrate) claimDriver() error {
+	// drivers of incomparable types can't be map keys
+	if m.databaseDrv == nil || !reflect.TypeOf(m.databaseDrv).Comparable() {
+		return nil
+	}
+	lockedDriversMu.Lock()
+	defer lockedDriversMu.Unlock()
+	if holder, ok := lockedDrivers[m.databaseDrv]; ok && holder != m {
+		return ErrDriverInUse
+	}
+	lockedDrivers[m.databaseDrv] = m
+	return nil
+}
+
+// releaseDriver removes the registration of claimDriver.
+func (m *Migrate) releaseDriver() {
+	if m.databaseDrv == nil || !reflect.TypeOf(m.databaseDrv).Comparable() {
+		return
+	}
+	lockedDriversMu.Lock()
+	defer lockedDriversMu.Unlock()
+	if lockedDrivers[m.databaseDrv] == m {
+		delete(lockedDrivers, m.databaseDrv)
+	}
+}
+
+// lockOnce tries to acquire the lock once, waiting up to LockTimeout.
+// The caller must hold isLockedMu.
+func (m *Migrate) lockOnce(parent context.Context) error {
+	if err := parent.Err(); err != nil {
+		return err
+	}
+
 	// create done channel, used in the timeout goroutine
 	done := make(chan bool, 1)
 	defer func() {
@@ -903,6 +2413,10 @@ func (m *Migrate) lock() error {
 	// use errchan to signal error back to this context
 	errchan := make(chan error, 2)
 
+	// ctx is cancelled on timeout, aborting drivers implementing database.LockerContext
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
 	// start timeout goroutine
 	timeout := time.After(m.LockTimeout)
 	go func() {
@@ -912,6 +2426,10 @@ func (m *Migrate) lock() error {
 				return
 			case <-timeout:
 				errchan <- ErrLockTimeout
+				cancel()
+				return
+			case <-parent.Done():
+				errchan <- parent.Err()
 				return
 			}
 		}
@@ -919,7 +2437,13 @@ func (m *Migrate) lock() error {
 
 	// now try to acquire the lock
 	go func() {
-		if err := m.databaseDrv.Lock(); err != nil {
+		var err error
+		if locker, ok := m.databaseDrv.(database.LockerContext); ok {
+			err = locker.LockContext(ctx)
+		} else {
+			err = m.databaseDrv.Lock()
+		}
+		if err != nil {
 			errchan <- err
 		} else {
 			errchan <- nil
@@ -930,10 +2454,104 @@ func (m *Migrate) lock() error {
 	err := <-errchan
 	if err == nil {
 		m.isLocked = true
+		if l, ok := m.leveledLogger(); ok {
+			l.Debug("lock acquired")
+		}
+		if m.OnLock != nil {
+			m.OnLock(m.now())
+		}
+	}
+	return err
+}
+
+// TryLock reports whether the database lock is free, e.g. to tell an
+// operator that a migration is already in progress instead of waiting for
+// it. If the database driver implements database.TryLocker, the lock is
+// tried without waiting. Otherwise TryLock waits up to LockTimeout and
+// reports false if that passes. TryLock releases the lock before returning.
+func (m *Migrate) TryLock() (bool, error) {
+	locker, ok := m.databaseDrv.(database.TryLocker)
+	if !ok {
+		if err := m.lock(); err != nil {
+			if errors.Is(err, ErrLocked) || errors.Is(err, database.ErrLocked) || errors.Is(err, ErrLockTimeout) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, m.unlock()
+	}
+
+	m.isLockedMu.Lock()
+	defer m.isLockedMu.Unlock()
+
+	// this instance is running migrations
+	if m.isLocked {
+		return false, nil
+	}
+
+	if err := m.claimDriver(); err != nil {
+		return false, err
+	}
+	defer m.releaseDriver()
+	locked, err := locker.TryLock()
+	if err != nil || !locked {
+		return false, err
+	}
+	if m.OnLock != nil {
+		m.OnLock(m.now())
+	}
+	if err := m.databaseDrv.Unlock(); err != nil {
+		return true, err
+	}
+	if m.OnUnlock != nil {
+		m.OnUnlock(m.now())
+	}
+	return true, nil
+}
+
+// databaseVersion returns the version of the database, retrying transient
+// errors as set by SetRetry.
+func (m *Migrate) databaseVersion() (version int, dirty bool, err error) {
+	err = m.retry(context.Background(), func() (err error) {
+		version, dirty, err = m.store().Version()
+		return err
+	})
+	return version, dirty, err
+}
+
+// retry calls f until it succeeds, fails with an error that isn't transient
+// or the attempts set by SetRetry are used up.
+func (m *Migrate) retry(ctx context.Context, f func() error) error {
+	return m.retryIf(ctx, m.isTransient, f)
+}
+
+// retryIf is retry, with the errors transient returns true for retried.
+func (m *Migrate) retryIf(ctx context.Context, transient func(err error) bool, f func() error) error {
+	err := f()
+	for attempt := 1; err != nil && attempt <= m.retryAttempts && transient(err); attempt++ {
+		m.logVerbosePrintf("Retrying after transient error (attempt %v): %v\n", attempt, err)
+		if m.retryBackoff != nil {
+			select {
+			case <-time.After(m.retryBackoff(attempt)):
+			case <-ctx.Done():
+				return err
+			}
+		}
+		err = f()
 	}
 	return err
 }
 
+// isTransient reports whether err is transient by IsTransient or the
+// database driver, if it implements database.TransientErrorer.
+func (m *Migrate) isTransient(err error) bool {
+	if IsTransient(err) {
+		return true
+	}
+	t, ok := m.databaseDrv.(database.TransientErrorer)
+	return ok && t.IsTransient(err)
+}
+
 // unlock is a thread safe helper function to unlock the database.
 // It should be called as early as possible when no more migrations are
 // expected to be executed.
@@ -947,9 +2565,41 @@ func (m *Migrate) unlock() error {
 	}
 
 	m.isLocked = false
+	m.releaseDriver()
+	if l, ok := m.leveledLogger(); ok {
+		l.Debug("lock released")
+	}
+	if m.OnUnlock != nil {
+		m.OnUnlock(m.now())
+	}
 	return nil
 }
 
+// lockRead locks the database for the read-only operations if LockReads
+// is set. Operations changing the database always lock.
+func (m *Migrate) lockRead() error {
+	if !m.LockReads {
+		return nil
+	}
+	return m.lock()
+}
+
+// unlockRead releases the lock taken by lockRead.
+func (m *Migrate) unlockRead() error {
+	if !m.LockReads {
+		return nil
+	}
+	return m.unlock()
+}
+
+// unlockReadErr is unlockErr for the lock taken by lockRead.
+func (m *Migrate) unlockReadErr(prevErr error) error {
+	if !m.LockReads {
+		return prevErr
+	}
+	return m.unlockErr(prevErr)
+}
+
 // unlockErr calls unlock and returns a combined error
 // if a prevErr is not nil.
 func (m *Migrate) unlockErr(prevErr error) error {
@@ -975,7 +2625,15 @@ func (m *Migrate) logVerbosePrintf(format string, v ...interface{}) {
 
 // logErr writes error to m.Log if not nil
 func (m *Migrate) logErr(err error) {
-	if m.Log != nil {
+	if l, ok := m.leveledLogger(); ok {
+		l.Error("error", "error", err)
+	} else if m.Log != nil {
 		m.Log.Printf("error: %v", err)
 	}
 }
+
+// leveledLogger returns m.Log if it implements LeveledLogger
+func (m *Migrate) leveledLogger() (LeveledLogger, bool) {
+	l, ok := m.Log.(LeveledLogger)
+	return l, ok
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"time"
+
+	"github.com/golang-migrate/migrate/v4/source"
 )
 
 // DefaultBufferSize sets the in memory buffer size (in Bytes) for every
@@ -54,6 +56,16 @@ type Migration struct {
 
 	// BytesRead holds the number of Bytes read from the migration source.
 	BytesRead int64
+
+	// Metadata is set by the ContentTransformer of Migrate once the
+	// migration was read, see Migrate.SetContentTransformer.
+	Metadata Metadata
+
+	// dir is the direction set by Migrate, which knows the version order.
+	dir source.Direction
+
+	// clock returns the current time, see Migrate.SetClock.
+	clock func() time.Time
 }
 
 // NewMigration returns a new Migration and sets the body, identifier,
@@ -75,12 +87,19 @@ type Migration struct {
 // be nil. Nil in this case is represented by -1 (because type int).
 func NewMigration(body io.ReadCloser, identifier string,
 	version uint, targetVersion int) (*Migration, error) {
-	tnow := time.Now()
+	return newMigration(body, identifier, version, targetVersion, time.Now)
+}
+
+// newMigration is NewMigration, taking the timestamps from clock.
+func newMigration(body io.ReadCloser, identifier string,
+	version uint, targetVersion int, clock func() time.Time) (*Migration, error) {
+	tnow := clock()
 	m := &Migration{
 		Identifier:    identifier,
 		Version:       version,
 		TargetVersion: targetVersion,
 		Scheduled:     tnow,
+		clock:         clock,
 	}
 
 	if body == nil {
@@ -107,10 +126,30 @@ func (m *Migration) String() string {
 	return fmt.Sprintf("%v [%v=>%v]", m.Identifier, m.Version, m.TargetVersion)
 }
 
+// direction returns whether this migration goes up or down.
+func (m *Migration) direction() source.Direction {
+	if m.dir != "" {
+		return m.dir
+	}
+	if m.TargetVersion < int(m.Version) {
+		return source.Down
+	}
+	return source.Up
+}
+
+// now returns the current time of the clock of the Migrate, or time.Now
+// for migrations created otherwise.
+func (m *Migration) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock()
+}
+
 // LogString returns a string describing this migration to humans.
 func (m *Migration) LogString() string {
 	directionStr := "u"
-	if m.TargetVersion < int(m.Version) {
+	if m.direction() == source.Down {
 		directionStr = "d"
 	}
 	return fmt.Sprintf("%v/%v %v", m.Version, directionStr, m.Identifier)
@@ -123,7 +162,7 @@ func (m *Migration) Buffer() error {
 		return nil
 	}
 
-	m.StartedBuffering = time.Now()
+	m.StartedBuffering = m.now()
 
 	b := bufio.NewReaderSize(m.Body, int(m.BufferSize))
 
@@ -133,7 +172,7 @@ func (m *Migration) Buffer() error {
 		return err
 	}
 
-	m.FinishedBuffering = time.Now()
+	m.FinishedBuffering = m.now()
 
 	// write to bufferWriter, this will block until
 	// something starts reading from m.Buffer
@@ -142,7 +181,7 @@ func (m *Migration) Buffer() error {
 		return err
 	}
 
-	m.FinishedReading = time.Now()
+	m.FinishedReading = m.now()
 	m.BytesRead = n
 
 	// close bufferWriter so Buffer knows that there is no
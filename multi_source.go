@@ -0,0 +1,109 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/hashicorp/go-multierror"
+)
+
+// multiSourceName is the source name of a Migrate created by
+// NewWithMultiSource.
+const multiSourceName = "multi"
+
+// multiSource is a source driver merging the versions of several sources.
+// Every version belongs to a single source, which reads its migrations.
+type multiSource struct {
+	sources []source.Driver
+	// owners are the positions of the sources of the versions
+	owners   map[uint]int
+	versions []uint
+}
+
+// newMultiSource lists the versions of sources, returning an
+// ErrVersionCollision if a version is in more than one source.
+func newMultiSource(sources []source.Driver) (*multiSource, error) {
+	s := &multiSource{sources: sources, owners: make(map[uint]int)}
+	for i, src := range sources {
+		versions, err := listVersions(src)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			if other, ok := s.owners[version]; ok {
+				return nil, ErrVersionCollision{Version: version, Source: other, Other: i}
+			}
+			s.owners[version] = i
+			s.versions = append(s.versions, version)
+		}
+	}
+	sort.Slice(s.versions, func(i, j int) bool { return s.versions[i] < s.versions[j] })
+	return s, nil
+}
+
+// Open is part of source.Driver, a multiSource is created by
+// NewWithMultiSource only.
+func (s *multiSource) Open(url string) (source.Driver, error) {
+	return nil, errors.New("Open() cannot be called on the multi source driver")
+}
+
+// Close closes all sources.
+func (s *multiSource) Close() error {
+	var result error
+	for _, src := range s.sources {
+		if err := src.Close(); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
+}
+
+// Versions is part of source.Lister.
+func (s *multiSource) Versions() ([]uint, error) {
+	return append([]uint(nil), s.versions...), nil
+}
+
+func (s *multiSource) First() (version uint, err error) {
+	if len(s.versions) == 0 {
+		return 0, &os.PathError{Op: "first", Path: multiSourceName, Err: os.ErrNotExist}
+	}
+	return s.versions[0], nil
+}
+
+func (s *multiSource) Prev(version uint) (prevVersion uint, err error) {
+	if i, ok := s.index(version); ok && i > 0 {
+		return s.versions[i-1], nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("prev for version %v", version), Path: multiSourceName, Err: os.ErrNotExist}
+}
+
+func (s *multiSource) Next(version uint) (nextVersion uint, err error) {
+	if i, ok := s.index(version); ok && i < len(s.versions)-1 {
+		return s.versions[i+1], nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("next for version %v", version), Path: multiSourceName, Err: os.ErrNotExist}
+}
+
+// index returns the position of version in versions.
+func (s *multiSource) index(version uint) (int, bool) {
+	i := sort.Search(len(s.versions), func(i int) bool { return s.versions[i] >= version })
+	return i, i < len(s.versions) && s.versions[i] == version
+}
+
+func (s *multiSource) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
+	if i, ok := s.owners[version]; ok {
+		return s.sources[i].ReadUp(version)
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read up for version %v", version), Path: multiSourceName, Err: os.ErrNotExist}
+}
+
+func (s *multiSource) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
+	if i, ok := s.owners[version]; ok {
+		return s.sources[i].ReadDown(version)
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read down for version %v", version), Path: multiSourceName, Err: os.ErrNotExist}
+}
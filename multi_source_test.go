@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	dStub "github.com/golang-migrate/migrate/v4/database/stub"
+	"github.com/golang-migrate/migrate/v4/source"
+	sStub "github.com/golang-migrate/migrate/v4/source/stub"
+)
+
+// stubSource returns a stub source with an up and a down migration per version.
+func stubSource(t *testing.T, versions ...uint) source.Driver {
+	src, err := (&sStub.Stub{}).Open("stub://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations := source.NewMigrations()
+	for _, v := range versions {
+		migrations.Append(&source.Migration{Version: v, Direction: source.Up, Identifier: fmt.Sprintf("CREATE %v", v)})
+		migrations.Append(&source.Migration{Version: v, Direction: source.Down, Identifier: fmt.Sprintf("DROP %v", v)})
+	}
+	src.(*sStub.Stub).Migrations = migrations
+	return src
+}
+
+func TestNewWithMultiSource(t *testing.T) {
+	dbDrv, err := (&dStub.Stub{}).Open("stub://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	platform := stubSource(t, 1, 3, 6)
+	service := stubSource(t, 2, 4, 5)
+
+	m, err := NewWithMultiSource([]source.Driver{platform, service}, dbDrv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the versions are merged, each one is read from its own source
+	src := m.sourceDrv
+	expected := []uint{1, 2, 3, 4, 5, 6}
+	v, err := src.First()
+	for i := 0; err == nil; i++ {
+		if v != expected[i] {
+			t.Fatalf("expected version %v, got %v", expected[i], v)
+		}
+		if i > 0 {
+			if prev, err := src.Prev(v); err != nil || prev != expected[i-1] {
+				t.Errorf("expected prev %v of %v, got %v, %v", expected[i-1], v, prev, err)
+			}
+		}
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+	if _, _, err := src.ReadUp(7); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Steps(-2); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 0, migrationSequence{
+		mr("CREATE 1"), mr("CREATE 2"), mr("CREATE 3"), mr("CREATE 4"), mr("CREATE 5"), mr("CREATE 6"),
+		mr("DROP 6"), mr("DROP 5"),
+	}, dbDrv.(*dStub.Stub))
+	if v, dirty, err := m.Version(); err != nil || v != 4 || dirty {
+		t.Errorf("expected version 4, got %v, %v, %v", v, dirty, err)
+	}
+
+	if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+		t.Error(srcErr, dbErr)
+	}
+}
+
+func TestNewWithMultiSourceCollision(t *testing.T) {
+	dbDrv, err := (&dStub.Stub{}).Open("stub://")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewWithMultiSource([]source.Driver{stubSource(t, 1, 2), stubSource(t, 3), stubSource(t, 4, 2)}, dbDrv)
+	var collision ErrVersionCollision
+	if !errors.As(err, &collision) {
+		t.Fatalf("expected ErrVersionCollision, got %v", err)
+	}
+	if expected := (ErrVersionCollision{Version: 2, Source: 0, Other: 2}); collision != expected {
+		t.Errorf("expected %v, got %v", expected, collision)
+	}
+}
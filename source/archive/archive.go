@@ -0,0 +1,104 @@
+// Package archive reads migrations from a .zip or .tar.gz archive, e.g. to
+// distribute the migrations of an application as a single artifact.
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	nurl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+func init() {
+	source.Register("archive", &Archive{})
+}
+
+// Archive is a source driver reading the migrations of an archive through
+// iofs.PartialDriver, so that entry names are parsed like the file names of
+// the file source.
+type Archive struct {
+	iofs.PartialDriver
+}
+
+// Open opens an archive:///path/to/migrations.zip or archive://relative/path
+// url. The format is detected by the extension, either .zip, .tar.gz or .tgz.
+// The query parameter dir selects the directory of the archive holding the
+// migrations, defaulting to its root.
+func (a *Archive) Open(url string) (source.Driver, error) {
+	u, err := nurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	// host might be `.`
+	p := u.Host + u.Path
+	if p == "" {
+		return nil, fmt.Errorf("no archive path in %v", url)
+	}
+	dir := u.Query().Get("dir")
+	if dir == "" {
+		dir = "."
+	}
+
+	switch {
+	case strings.HasSuffix(p, ".zip"):
+		zr, err := zip.OpenReader(filepath.FromSlash(p))
+		if err != nil {
+			return nil, err
+		}
+		na := &Archive{}
+		// Close of the driver closes the archive as well
+		if err := na.Init(zr, dir); err != nil {
+			zr.Close()
+			return nil, err
+		}
+		return na, nil
+
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		f, err := os.Open(filepath.FromSlash(p))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return NewTarGz(f, dir)
+
+	default:
+		return nil, fmt.Errorf("unsupported archive %v, expected .zip, .tar.gz or .tgz", p)
+	}
+}
+
+// New returns a driver reading the migrations in the directory dir of fsys,
+// e.g. a *zip.Reader.
+func New(fsys fs.FS, dir string) (source.Driver, error) {
+	a := &Archive{}
+	if err := a.Init(fsys, dir); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewZip returns a driver reading the migrations in the directory dir of the
+// zip archive r of size bytes.
+func NewZip(r io.ReaderAt, size int64, dir string) (source.Driver, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return New(zr, dir)
+}
+
+// NewTarGz returns a driver reading the migrations in the directory dir of
+// the gzip compressed tar archive r. The archive is read into memory.
+func NewTarGz(r io.Reader, dir string) (source.Driver, error) {
+	fsys, err := readTarGz(r)
+	if err != nil {
+		return nil, err
+	}
+	return New(fsys, dir)
+}
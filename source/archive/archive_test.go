@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	st "github.com/golang-migrate/migrate/v4/source/testing"
+)
+
+// files are the migrations expected by st.Test, in the directory
+// migrations of the archives, and a file that isn't a migration.
+var files = []string{
+	"README.md",
+	"migrations/1_foobar.up.sql",
+	"migrations/1_foobar.down.sql",
+	"migrations/3_foobar.up.sql",
+	"migrations/4_foobar.up.sql",
+	"migrations/4_foobar.down.sql",
+	"migrations/5_foobar.down.sql",
+	"migrations/7_foobar.up.sql",
+	"migrations/7_foobar.down.sql",
+}
+
+func TestZip(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "migrations.zip")
+	mustWriteFile(t, p, mustZip(t))
+
+	a := &Archive{}
+	d, err := a.Open("archive://" + p + "?dir=migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Test(t, d)
+}
+
+func TestNewZip(t *testing.T) {
+	data := mustZip(t)
+	d, err := NewZip(bytes.NewReader(data), int64(len(data)), "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Test(t, d)
+}
+
+func TestTarGz(t *testing.T) {
+	for _, name := range []string{"migrations.tar.gz", "migrations.tgz"} {
+		t.Run(name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), name)
+			mustWriteFile(t, p, mustTarGz(t))
+
+			a := &Archive{}
+			d, err := a.Open("archive://" + p + "?dir=migrations")
+			if err != nil {
+				t.Fatal(err)
+			}
+			st.Test(t, d)
+		})
+	}
+}
+
+func TestNewTarGz(t *testing.T) {
+	d, err := NewTarGz(bytes.NewReader(mustTarGz(t)), "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Test(t, d)
+
+	if _, err := NewTarGz(bytes.NewReader(mustTarGz(t)), "missing"); err == nil {
+		t.Fatal("expected err for missing directory")
+	}
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "migrations.rar")
+	mustWriteFile(t, p, nil)
+
+	a := &Archive{}
+	if _, err := a.Open("archive://" + p); err == nil {
+		t.Fatal("expected err for unsupported archive")
+	}
+}
+
+func mustZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func mustTarGz(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, name := range files {
+		hdr := &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(name)),
+			ModTime:  time.Now(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func mustWriteFile(t *testing.T, p string, data []byte) {
+	if err := ioutil.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tarFS is an in-memory fs.FS of the regular files of a tar archive.
+type tarFS struct {
+	// files holds the files by their cleaned path
+	files map[string]*tarEntry
+	// dirs holds the sorted entries of every directory by its path
+	dirs map[string][]fs.DirEntry
+}
+
+// readTarGz reads the gzip compressed tar archive r into a tarFS.
+func readTarGz(r io.Reader) (*tarFS, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	t := &tarFS{
+		files: make(map[string]*tarEntry),
+		dirs:  map[string][]fs.DirEntry{".": nil},
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			t.addDir(name)
+		case tar.TypeReg:
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			e := &tarEntry{name: path.Base(name), data: data, modTime: hdr.ModTime}
+			dir := path.Dir(name)
+			t.addDir(dir)
+			t.files[name] = e
+			t.dirs[dir] = append(t.dirs[dir], e)
+		}
+	}
+	for _, entries := range t.dirs {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	}
+	return t, nil
+}
+
+// addDir adds the directory name and its parents.
+func (t *tarFS) addDir(name string) {
+	if _, ok := t.dirs[name]; ok {
+		return
+	}
+	parent := path.Dir(name)
+	t.addDir(parent)
+	t.dirs[name] = nil
+	t.dirs[parent] = append(t.dirs[parent], &tarEntry{name: path.Base(name), dir: true})
+}
+
+func (t *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if e, ok := t.files[name]; ok {
+		return &tarFile{tarEntry: e, r: bytes.NewReader(e.data)}, nil
+	}
+	if _, ok := t.dirs[name]; ok {
+		return &tarFile{tarEntry: &tarEntry{name: path.Base(name), dir: true}, r: bytes.NewReader(nil)}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (t *tarFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := t.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// tarEntry is a file or directory of a tarFS, serving as both its
+// fs.DirEntry and fs.FileInfo.
+type tarEntry struct {
+	name    string
+	data    []byte
+	modTime time.Time
+	dir     bool
+}
+
+func (e *tarEntry) Name() string               { return e.name }
+func (e *tarEntry) Size() int64                { return int64(len(e.data)) }
+func (e *tarEntry) ModTime() time.Time         { return e.modTime }
+func (e *tarEntry) IsDir() bool                { return e.dir }
+func (e *tarEntry) Sys() interface{}           { return nil }
+func (e *tarEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e *tarEntry) Type() fs.FileMode          { return e.Mode().Type() }
+
+func (e *tarEntry) Mode() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type tarFile struct {
+	*tarEntry
+	r *bytes.Reader
+}
+
+func (f *tarFile) Stat() (fs.FileInfo, error) { return f.tarEntry, nil }
+func (f *tarFile) Read(p []byte) (int, error) { return f.r.Read(p) }
+func (f *tarFile) Close() error               { return nil }
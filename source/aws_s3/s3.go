@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -36,7 +37,12 @@ func (s *s3Driver) Open(folder string) (source.Driver, error) {
 		return nil, err
 	}
 
-	sess, err := session.NewSession()
+	awsConfig, err := parseAWSConfig(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := session.NewSession(awsConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +81,39 @@ func parseURI(uri string) (*Config, error) {
 	}, nil
 }
 
+// parseAWSConfig returns the AWS SDK config set by the query of uri, to use
+// S3-compatible stores like MinIO. Without query, the SDK defaults are kept.
+func parseAWSConfig(uri string) (*aws.Config, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+
+	config := aws.NewConfig()
+	if endpoint := q.Get("endpoint"); endpoint != "" {
+		config = config.WithEndpoint(endpoint)
+	}
+	if region := q.Get("region"); region != "" {
+		config = config.WithRegion(region)
+	}
+	if s := q.Get("disable_ssl"); s != "" {
+		disableSSL, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse option disable_ssl: %w", err)
+		}
+		config = config.WithDisableSSL(disableSSL)
+	}
+	if s := q.Get("force_path_style"); s != "" {
+		forcePathStyle, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse option force_path_style: %w", err)
+		}
+		config = config.WithS3ForcePathStyle(forcePathStyle)
+	}
+	return config, nil
+}
+
 func (s *s3Driver) loadMigrations() error {
 	output, err := s.s3client.ListObjects(&s3.ListObjectsInput{
 		Bucket:    aws.String(s.config.Bucket),
@@ -90,8 +129,8 @@ func (s *s3Driver) loadMigrations() error {
 		if err != nil {
 			continue
 		}
-		if !s.migrations.Append(m) {
-			return fmt.Errorf("unable to parse file %v", aws.StringValue(object.Key))
+		if err := s.migrations.AppendUnique(m, nil); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -101,6 +140,11 @@ func (s *s3Driver) Close() error {
 	return nil
 }
 
+// Versions is part of source.Lister, the versions are listed once by Open.
+func (s *s3Driver) Versions() ([]uint, error) {
+	return s.migrations.Versions(), nil
+}
+
 func (s *s3Driver) First() (uint, error) {
 	v, ok := s.migrations.First()
 	if !ok {
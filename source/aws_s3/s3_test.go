@@ -3,6 +3,9 @@ package awss3
 import (
 	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
@@ -125,3 +128,95 @@ func (s *fakeS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error
 	}
 	return nil, errors.New("object not found")
 }
+
+func TestOpenWithEndpoint(t *testing.T) {
+	for k, v := range map[string]string{
+		"AWS_ACCESS_KEY_ID":     "key",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+	} {
+		prev, ok := os.LookupEnv(k)
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+		defer func(k string) {
+			if ok {
+				os.Setenv(k, prev)
+			} else {
+				os.Unsetenv(k)
+			}
+		}(k)
+	}
+
+	objects := map[string]string{
+		"/some-bucket/migrations/1_foobar.up.sql":   "1 up",
+		"/some-bucket/migrations/1_foobar.down.sql": "1 down",
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/some-bucket" || r.URL.Path == "/some-bucket/" {
+			assert.Equal(t, "migrations/", r.URL.Query().Get("prefix"))
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>some-bucket</Name>
+  <Prefix>migrations/</Prefix>
+  <Contents><Key>migrations/1_foobar.up.sql</Key></Contents>
+  <Contents><Key>migrations/1_foobar.down.sql</Key></Contents>
+</ListBucketResult>`))
+			return
+		}
+		if data, ok := objects[r.URL.Path]; ok {
+			_, _ = w.Write([]byte(data))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	endpoint := strings.TrimPrefix(ts.URL, "http://")
+	s := &s3Driver{}
+	d, err := s.Open("s3://some-bucket/migrations?endpoint=" + endpoint + "&region=us-east-1&disable_ssl=true&force_path_style=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := d.First()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint(1), version)
+
+	r, identifier, err := d.ReadUp(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "foobar", identifier)
+	assert.Equal(t, "1 up", string(body))
+}
+
+func TestParseAWSConfig(t *testing.T) {
+	config, err := parseAWSConfig("s3://migration-bucket/production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, config.Endpoint)
+	assert.Nil(t, config.Region)
+	assert.Nil(t, config.DisableSSL)
+	assert.Nil(t, config.S3ForcePathStyle)
+
+	config, err = parseAWSConfig("s3://migration-bucket/production?endpoint=https://minio:9000&region=eu-west-1&disable_ssl=false&force_path_style=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://minio:9000", aws.StringValue(config.Endpoint))
+	assert.Equal(t, "eu-west-1", aws.StringValue(config.Region))
+	assert.False(t, aws.BoolValue(config.DisableSSL))
+	assert.True(t, aws.BoolValue(config.S3ForcePathStyle))
+
+	if _, err := parseAWSConfig("s3://migration-bucket?force_path_style=maybe"); err == nil {
+		t.Fatal("expected error for invalid force_path_style")
+	}
+}
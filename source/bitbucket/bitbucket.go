@@ -113,8 +113,8 @@ func (b *Bitbucket) readDirectory() error {
 		if err != nil {
 			continue // ignore files that we can't parse
 		}
-		if !b.migrations.Append(m) {
-			return fmt.Errorf("unable to parse file %v", fi.Path)
+		if err := b.migrations.AppendUnique(m, nil); err != nil {
+			return err
 		}
 	}
 
@@ -131,6 +131,11 @@ func (b *Bitbucket) Close() error {
 	return nil
 }
 
+// Versions is part of source.Lister, the versions are listed once by Open.
+func (b *Bitbucket) Versions() ([]uint, error) {
+	return b.migrations.Versions(), nil
+}
+
 func (b *Bitbucket) First() (version uint, er error) {
 	b.ensureFields()
 
@@ -5,10 +5,12 @@
 package source
 
 import (
+	"context"
 	"fmt"
 	"io"
 	nurl "net/url"
 	"sync"
+	"time"
 )
 
 var driversMu sync.RWMutex
@@ -72,6 +74,47 @@ type Driver interface {
 	ReadDown(version uint) (r io.ReadCloser, identifier string, err error)
 }
 
+// FileInfo is the size and modification time of a migration file.
+type FileInfo struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Info describes the migration files of a version. Up or Down is nil if the
+// version has no such migration.
+type Info struct {
+	Up   *FileInfo
+	Down *FileInfo
+}
+
+// Stater is an optional interface a Driver can implement, if it can tell
+// whether migration files changed without reading them.
+type Stater interface {
+	// Stat returns the Info of version.
+	// If there is no migration available for this version,
+	// it must return os.ErrNotExist.
+	Stat(version uint) (Info, error)
+}
+
+// Lister is an optional interface a Driver can implement, if it can return
+// all of its versions with a single call, e.g. a single listing of a remote
+// directory. Migrate prefers it over stepping through the versions with
+// First, Next and Prev.
+type Lister interface {
+	// Versions returns all versions available to the driver in ascending
+	// order.
+	Versions() ([]uint, error)
+}
+
+// Executor is an optional interface the body returned by ReadUp or ReadDown
+// can implement, if the migration isn't run by the database driver, e.g. a
+// migration written in Go, see source/gomigrate. Migrate calls Execute
+// instead of passing the body to the database driver.
+type Executor interface {
+	// Execute applies the migration.
+	Execute(ctx context.Context) error
+}
+
 // Open returns a new driver instance.
 func Open(url string) (Driver, error) {
 	u, err := nurl.Parse(url)
@@ -1,15 +1,30 @@
 package source
 
-import "os"
+import (
+	"fmt"
+	"os"
+)
 
 // ErrDuplicateMigration is an error type for reporting duplicate migration
 // files.
 type ErrDuplicateMigration struct {
 	Migration
+	// FileInfo describes the file of Migration, it may be nil for sources
+	// not backed by a file system.
 	os.FileInfo
+	// Conflict is the migration of the same version and direction found
+	// before Migration, if known.
+	Conflict *Migration
 }
 
 // Error implements error interface.
 func (e ErrDuplicateMigration) Error() string {
-	return "duplicate migration file: " + e.Name()
+	name := e.Migration.Raw
+	if e.FileInfo != nil {
+		name = e.Name()
+	}
+	if e.Conflict == nil {
+		return "duplicate migration file: " + name
+	}
+	return fmt.Sprintf("duplicate migration files for version %v %v: %v and %v", e.Version, e.Direction, e.Conflict.Raw, e.Migration.Raw)
 }
@@ -4,6 +4,7 @@ import (
 	nurl "net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
@@ -19,21 +20,46 @@ type File struct {
 	path string
 }
 
+// Open opens a file:// url. With the query parameter recursive=true, the
+// migrations of all subdirectories are read as well, see
+// iofs.PartialDriver.InitRecursive.
 func (f *File) Open(url string) (source.Driver, error) {
 	p, err := parseURL(url)
 	if err != nil {
 		return nil, err
 	}
+	recursive, err := parseRecursive(url)
+	if err != nil {
+		return nil, err
+	}
 	nf := &File{
 		url:  url,
 		path: p,
 	}
-	if err := nf.Init(os.DirFS(p), "."); err != nil {
+	initDriver := nf.Init
+	if recursive {
+		initDriver = nf.InitRecursive
+	}
+	if err := initDriver(gzipFS{os.DirFS(p)}, "."); err != nil {
 		return nil, err
 	}
 	return nf, nil
 }
 
+// parseRecursive returns the recursive query parameter of url, false if
+// not set.
+func parseRecursive(url string) (bool, error) {
+	u, err := nurl.Parse(url)
+	if err != nil {
+		return false, err
+	}
+	v := u.Query().Get("recursive")
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
 func parseURL(url string) (string, error) {
 	u, err := nurl.Parse(url)
 	if err != nil {
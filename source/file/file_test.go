@@ -1,14 +1,18 @@
 package file
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"testing"
 
+	"github.com/golang-migrate/migrate/v4/source"
 	st "github.com/golang-migrate/migrate/v4/source/testing"
 )
 
@@ -55,6 +59,108 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestGzip(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte("3 up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mustWriteFile(t, tmpDir, "0003_seed.up.sql.gz", buf.String())
+	mustWriteFile(t, tmpDir, "0003_seed.down.sql", "3 down")
+
+	f := &File{}
+	d, err := f.Open("file://" + tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []struct {
+		read         func(uint) (io.ReadCloser, string, error)
+		expectedBody string
+	}{
+		{d.ReadUp, "3 up"},
+		{d.ReadDown, "3 down"},
+	} {
+		r, identifier, err := v.read(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if identifier != "seed" {
+			t.Errorf("expected identifier seed, got %v", identifier)
+		}
+		if string(body) != v.expectedBody {
+			t.Errorf("expected body %q, got %q", v.expectedBody, body)
+		}
+	}
+}
+
+func TestStat(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	mustWriteFile(t, tmpDir, "1_foobar.up.sql", "1 up")
+	mustWriteFile(t, tmpDir, "1_foobar.down.sql", "1 down, longer")
+	mustWriteFile(t, tmpDir, "3_foobar.up.sql", "3 up")
+
+	f := &File{}
+	d, err := f.Open("file://" + tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stater, ok := d.(source.Stater)
+	if !ok {
+		t.Fatal("expected file driver to implement source.Stater")
+	}
+
+	assertFileInfo := func(name string, fi *source.FileInfo) {
+		t.Helper()
+		expected, err := os.Stat(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi == nil {
+			t.Fatalf("expected info for %v", name)
+		}
+		if fi.Size != expected.Size() {
+			t.Errorf("expected size %v of %v, got %v", expected.Size(), name, fi.Size)
+		}
+		if !fi.ModTime.Equal(expected.ModTime()) {
+			t.Errorf("expected mtime %v of %v, got %v", expected.ModTime(), name, fi.ModTime)
+		}
+	}
+
+	info, err := stater.Stat(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFileInfo("1_foobar.up.sql", info.Up)
+	assertFileInfo("1_foobar.down.sql", info.Down)
+
+	info, err = stater.Stat(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFileInfo("3_foobar.up.sql", info.Up)
+	if info.Down != nil {
+		t.Errorf("expected no down info for version 3, got %v", info.Down)
+	}
+
+	if _, err := stater.Stat(2); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist for version 2, got %v", err)
+	}
+}
+
 func TestOpenWithRelativePath(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -127,8 +233,94 @@ func TestOpenWithDuplicateVersion(t *testing.T) {
 
 	f := &File{}
 	_, err := f.Open("file://" + tmpDir)
-	if err == nil {
-		t.Fatal("expected err")
+	var dup source.ErrDuplicateMigration
+	if !errors.As(err, &dup) {
+		t.Fatalf("expected source.ErrDuplicateMigration, got %v", err)
+	}
+	// the directory is read in lexical order
+	expected := "duplicate migration files for version 1 up: 1_bar.up.sql and 1_foo.up.sql"
+	if err.Error() != expected {
+		t.Fatalf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestOpenRecursive(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"billing", "users"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, dir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWriteFile(t, filepath.Join(tmpDir, "users"), "1_users.up.sql", "1 up")
+	mustWriteFile(t, filepath.Join(tmpDir, "users"), "1_users.down.sql", "1 down")
+	mustWriteFile(t, filepath.Join(tmpDir, "billing"), "2_invoices.up.sql", "2 up")
+	mustWriteFile(t, filepath.Join(tmpDir, "users"), "3_roles.up.sql", "3 up")
+	mustWriteFile(t, filepath.Join(tmpDir, "billing"), "4_payments.up.sql", "4 up")
+
+	f := &File{}
+
+	// the flat default ignores subdirectories
+	d, err := f.Open("file://" + tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.First(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist, got %v", err)
+	}
+
+	d, err = f.Open("file://" + tmpDir + "?recursive=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []uint
+	v, err := d.First()
+	for err == nil {
+		versions = append(versions, v)
+		v, err = d.Next(v)
+	}
+	if fmt.Sprint(versions) != "[1 2 3 4]" {
+		t.Fatalf("expected versions [1 2 3 4], got %v", versions)
+	}
+
+	r, identifier, err := d.ReadUp(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identifier != "payments" || string(body) != "4 up" {
+		t.Fatalf("expected payments with body 4 up, got %v with body %q", identifier, body)
+	}
+	if _, _, err := d.ReadDown(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Open("file://" + tmpDir + "?recursive=maybe"); err == nil {
+		t.Fatal("expected err for invalid recursive")
+	}
+}
+
+func TestOpenRecursiveDuplicateVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"billing", "users"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, dir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWriteFile(t, filepath.Join(tmpDir, "billing"), "2_invoices.up.sql", "")
+	mustWriteFile(t, filepath.Join(tmpDir, "users"), "2_roles.down.sql", "")
+
+	f := &File{}
+	_, err := f.Open("file://" + tmpDir + "?recursive=true")
+	var dup source.ErrDuplicateMigration
+	if !errors.As(err, &dup) {
+		t.Fatalf("expected source.ErrDuplicateMigration, got %v", err)
+	}
+	if dup.Version != 2 {
+		t.Fatalf("expected duplicate version 2, got %v", dup.Version)
 	}
 }
 
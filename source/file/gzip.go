@@ -0,0 +1,48 @@
+package file
+
+import (
+	"compress/gzip"
+	"io/fs"
+	"path"
+)
+
+// gzipFS decompresses files ending in .gz when they are opened, so that
+// migrations like 3_seed.up.sql.gz are read like 3_seed.up.sql.
+type gzipFS struct {
+	fs.FS
+}
+
+func (g gzipFS) Open(name string) (fs.File, error) {
+	f, err := g.FS.Open(name)
+	if err != nil || path.Ext(name) != ".gz" {
+		return f, err
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &gzipFile{File: f, zr: zr}, nil
+}
+
+// Stat returns the FileInfo of the compressed file.
+func (g gzipFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(g.FS, name)
+}
+
+type gzipFile struct {
+	fs.File
+	zr *gzip.Reader
+}
+
+func (f *gzipFile) Read(p []byte) (int, error) {
+	return f.zr.Read(p)
+}
+
+func (f *gzipFile) Close() error {
+	if err := f.zr.Close(); err != nil {
+		f.File.Close()
+		return err
+	}
+	return f.File.Close()
+}
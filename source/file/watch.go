@@ -0,0 +1,120 @@
+package file
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is the time a Watcher waits for further changes
+// before signalling, so that a burst of editor saves signals once.
+const DefaultDebounce = 100 * time.Millisecond
+
+// Watcher signals changes to the .sql files of a migrations directory,
+// e.g. for a development tool re-running migrations on save.
+type Watcher struct {
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+	changes  chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Watch returns a Watcher for the directory of f. A debounce of zero
+// or less defaults to DefaultDebounce.
+func (f *File) Watch(debounce time.Duration) (*Watcher, error) {
+	return NewWatcher(f.url, debounce)
+}
+
+// NewWatcher returns a Watcher for the directory of the file:// url.
+// A debounce of zero or less defaults to DefaultDebounce.
+func NewWatcher(url string, debounce time.Duration) (*Watcher, error) {
+	p, err := parseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Add(p); err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		watcher:  fw,
+		debounce: debounce,
+		changes:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Changes returns a channel receiving a value after .sql files of the
+// directory were created, written, removed or renamed. Signals not yet
+// received are coalesced. The channel is closed by Close.
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Close stops watching and closes the Changes channel.
+func (w *Watcher) Close() error {
+	close(w.done)
+	err := w.watcher.Close()
+	w.wg.Wait()
+	return err
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+	defer close(w.changes)
+
+	timer := time.NewTimer(w.debounce)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Ext(event.Name) != ".sql" || event.Op == fsnotify.Chmod {
+				continue
+			}
+			// restart the debounce period
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(w.debounce)
+
+		case _, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+		case <-timer.C:
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
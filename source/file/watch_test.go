@@ -0,0 +1,63 @@
+package file
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, tmpDir, "1_foobar.up.sql", "1 up")
+
+	f := &File{}
+	d, err := f.Open("file://" + tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := d.(*File).Watch(50 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := w.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// files other than .sql files don't signal
+	mustWriteFile(t, tmpDir, "notes.txt", "foo")
+	select {
+	case <-w.Changes():
+		t.Fatal("unexpected signal for notes.txt")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// rapid saves are debounced into a single signal
+	for i := 0; i < 5; i++ {
+		mustWriteFile(t, tmpDir, "1_foobar.up.sql", "1 up changed")
+	}
+	mustWriteFile(t, tmpDir, "2_foobar.up.sql", "2 up")
+	select {
+	case <-w.Changes():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a signal after writing .sql files")
+	}
+	select {
+	case <-w.Changes():
+		t.Fatal("expected rapid saves to signal once")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestWatchClose(t *testing.T) {
+	w, err := NewWatcher("file://"+t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-w.Changes(); ok {
+		t.Fatal("expected Changes to be closed")
+	}
+}
@@ -0,0 +1,223 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// rateLimitThreshold is the number of remaining API requests below
+	// which requests are spread over the time until the rate limit resets.
+	rateLimitThreshold = 10
+	// maxRateLimitWait bounds the time waited before a single request.
+	maxRateLimitWait = time.Minute
+)
+
+// defaultCache is the in-memory cache shared by the drivers of Open, so
+// drivers opened again in the same process revalidate their files.
+var defaultCache = newMemoryCache()
+
+// cachedResponse is a response with an ETag.
+type cachedResponse struct {
+	etag string
+	// response is the response serialized by http.Response.Write
+	response []byte
+}
+
+// responseCache stores responses by URL.
+type responseCache interface {
+	get(key string) (cachedResponse, bool)
+	set(key string, r cachedResponse)
+}
+
+type memoryCache struct {
+	mu        sync.Mutex
+	responses map[string]cachedResponse
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{responses: make(map[string]cachedResponse)}
+}
+
+func (c *memoryCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.responses[key]
+	return r, ok
+}
+
+func (c *memoryCache) set(key string, r cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[key] = r
+}
+
+// dirCache stores responses in files of a directory, named by the hash of
+// their URL, so they survive the process. Its first line is the ETag.
+type dirCache struct {
+	dir string
+}
+
+func (c dirCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func (c dirCache) get(key string) (cachedResponse, bool) {
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return cachedResponse{}, false
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return cachedResponse{}, false
+	}
+	return cachedResponse{etag: string(data[:i]), response: data[i+1:]}, true
+}
+
+func (c dirCache) set(key string, r cachedResponse) {
+	data := append([]byte(r.etag+"\n"), r.response...)
+	// the cache is an optimization, failing to write it only costs a download
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return
+	}
+	_ = ioutil.WriteFile(c.path(key), data, 0600)
+}
+
+// cachingTransport revalidates the responses of GET requests with
+// If-None-Match, serving the cached response on 304 Not Modified, which
+// doesn't count against the GitHub rate limit. Once fewer than
+// rateLimitThreshold requests remain, requests are spread over the time
+// until the rate limit resets.
+type cachingTransport struct {
+	base  http.RoundTripper
+	cache responseCache
+
+	// sleep is time.Sleep, replaced by tests
+	sleep func(time.Duration)
+	now   func() time.Time
+
+	mu sync.Mutex
+	// wait is the time to wait before the next request
+	wait time.Duration
+}
+
+// NewCachingTransport returns a http.RoundTripper for the http.Client of a
+// github.Client revalidating files with ETags and backing off when the rate
+// limit is nearly used up. Responses are cached in memory, or in the
+// directory dir if not empty. base defaults to http.DefaultTransport.
+func NewCachingTransport(base http.RoundTripper, dir string) http.RoundTripper {
+	var cache responseCache = newMemoryCache()
+	if dir != "" {
+		cache = dirCache{dir: dir}
+	}
+	return newCachingTransport(base, cache)
+}
+
+func newCachingTransport(base http.RoundTripper, cache responseCache) *cachingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &cachingTransport{base: base, cache: cache, sleep: time.Sleep, now: time.Now}
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.backOff()
+
+	if req.Method != http.MethodGet {
+		return t.roundTrip(req)
+	}
+	key := req.URL.String()
+	cached, ok := t.cache.get(key)
+	if ok {
+		// RoundTrip must not modify the request
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := t.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		cachedResp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(cached.response)), req)
+		if err == nil {
+			resp.Body.Close()
+			return cachedResp, nil
+		}
+		// a corrupt cache entry is served by the next download
+		resp.Body.Close()
+		req.Header.Del("If-None-Match")
+		return t.roundTrip(req)
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var buf bytes.Buffer
+	stored := *resp
+	stored.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := stored.Write(&buf); err == nil {
+		t.cache.set(key, cachedResponse{etag: etag, response: buf.Bytes()})
+	}
+	return resp, nil
+}
+
+// roundTrip sends req, recording the rate limit of the response.
+func (t *cachingTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.recordRateLimit(resp.Header)
+	}
+	return resp, err
+}
+
+// recordRateLimit sets the time to wait before the next request from the
+// X-RateLimit-Remaining and X-RateLimit-Reset headers.
+func (t *cachingTransport) recordRateLimit(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	var wait time.Duration
+	if remaining < rateLimitThreshold {
+		wait = time.Unix(reset, 0).Sub(t.now()) / time.Duration(remaining+1)
+	}
+	if wait > maxRateLimitWait {
+		wait = maxRateLimitWait
+	}
+	t.mu.Lock()
+	t.wait = wait
+	t.mu.Unlock()
+}
+
+func (t *cachingTransport) backOff() {
+	t.mu.Lock()
+	wait := t.wait
+	t.mu.Unlock()
+	if wait > 0 {
+		t.sleep(wait)
+	}
+}
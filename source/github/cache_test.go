@@ -0,0 +1,155 @@
+package github
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	nurl "net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/google/go-github/v39/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// etagServer serves a migrations directory with ETags, counting the
+// responses with a body by path.
+type etagServer struct {
+	mu        sync.Mutex
+	downloads map[string]int
+	notMod    int
+}
+
+func (s *etagServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body string
+	switch r.URL.Path {
+	case "/api/v3/repos/owner/repo/contents/migrations":
+		body = `[{"type": "file", "name": "1_foobar.up.sql"}, {"type": "file", "name": "1_foobar.down.sql"}]`
+	case "/api/v3/repos/owner/repo/contents/migrations/1_foobar.up.sql":
+		body = `{"type": "file", "name": "1_foobar.up.sql", "content": "1 up"}`
+	case "/api/v3/repos/owner/repo/contents/migrations/1_foobar.down.sql":
+		body = `{"type": "file", "name": "1_foobar.down.sql", "content": "1 down"}`
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	etag := `"` + strconv.Itoa(len(body)) + `"`
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		s.notMod++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.downloads[r.URL.Path]++
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(body))
+}
+
+func newETagServer() (*etagServer, *httptest.Server) {
+	s := &etagServer{downloads: make(map[string]int)}
+	return s, httptest.NewServer(s)
+}
+
+func readUp(t *testing.T, d source.Driver, version uint) string {
+	r, _, err := d.ReadUp(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestETagCache(t *testing.T) {
+	s, ts := newETagServer()
+	defer ts.Close()
+
+	client := &http.Client{Transport: NewCachingTransport(nil, "")}
+	ghc, err := github.NewEnterpriseClient(ts.URL, ts.URL, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := WithInstance(ghc, &Config{Owner: "owner", Repo: "repo", Path: "migrations"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "1 up", readUp(t, d, 1))
+	}
+	assert.Equal(t, 1, s.downloads["/api/v3/repos/owner/repo/contents/migrations/1_foobar.up.sql"])
+	assert.Equal(t, 2, s.notMod)
+}
+
+func TestETagCacheDir(t *testing.T) {
+	s, ts := newETagServer()
+	defer ts.Close()
+	dir := t.TempDir()
+
+	url := "github://owner/repo/migrations?base_url=" + nurl.QueryEscape(ts.URL) + "&cache_dir=" + nurl.QueryEscape(dir)
+	// every Open is a run of its own
+	for i := 0; i < 2; i++ {
+		d, err := (&Github{}).Open(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "1 up", readUp(t, d, 1))
+	}
+	assert.Equal(t, map[string]int{
+		"/api/v3/repos/owner/repo/contents/migrations":                 1,
+		"/api/v3/repos/owner/repo/contents/migrations/1_foobar.up.sql": 1,
+	}, s.downloads)
+	assert.Equal(t, 2, s.notMod)
+}
+
+func TestRateLimitBackOff(t *testing.T) {
+	now := time.Unix(1000, 0)
+	reset := now.Add(30 * time.Second)
+	remaining := 100
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}))
+	defer ts.Close()
+
+	var waits []time.Duration
+	transport := newCachingTransport(nil, newMemoryCache())
+	transport.now = func() time.Time { return now }
+	transport.sleep = func(d time.Duration) { waits = append(waits, d) }
+	client := &http.Client{Transport: transport}
+
+	get := func() {
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// plenty of requests left
+	get()
+	get()
+	assert.Empty(t, waits)
+
+	// the remaining 2 requests are spread over the 30s until the reset
+	remaining = 2
+	get()
+	get()
+	assert.Equal(t, []time.Duration{10 * time.Second}, waits)
+
+	// the wait is bounded
+	remaining = 0
+	now = now.Add(-time.Hour)
+	get()
+	get()
+	assert.Equal(t, []time.Duration{10 * time.Second, 10 * time.Second, maxRateLimitWait}, waits)
+}
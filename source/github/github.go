@@ -48,8 +48,8 @@ func (g *Github) Open(url string) (source.Driver, error) {
 		return nil, err
 	}
 
-	// client defaults to http.DefaultClient
-	var client *http.Client
+	// transport defaults to http.DefaultTransport
+	var transport http.RoundTripper
 	if u.User != nil {
 		password, ok := u.User.Password()
 		if !ok {
@@ -58,12 +58,26 @@ func (g *Github) Open(url string) (source.Driver, error) {
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: password},
 		)
-		client = oauth2.NewClient(context.Background(), ts)
+		transport = oauth2.NewClient(context.Background(), ts).Transport
 
 	}
+	var cache responseCache = defaultCache
+	if dir := u.Query().Get("cache_dir"); dir != "" {
+		cache = dirCache{dir: dir}
+	}
+	client := &http.Client{Transport: newCachingTransport(transport, cache)}
+
+	ghc := github.NewClient(client)
+	if baseURL := u.Query().Get("base_url"); baseURL != "" {
+		// GitHub Enterprise, "/api/v3/" is appended to baseURL if missing
+		ghc, err = github.NewEnterpriseClient(baseURL, baseURL, client)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	gn := &Github{
-		client:     github.NewClient(client),
+		client:     ghc,
 		migrations: source.NewMigrations(),
 		options:    &github.RepositoryContentGetOptions{Ref: u.Fragment},
 	}
@@ -126,8 +140,8 @@ func (g *Github) readDirectory() error {
 		if err != nil {
 			continue // ignore files that we can't parse
 		}
-		if !g.migrations.Append(m) {
-			return fmt.Errorf("unable to parse file %v", *fi.Name)
+		if err := g.migrations.AppendUnique(m, nil); err != nil {
+			return err
 		}
 	}
 
@@ -144,6 +158,11 @@ func (g *Github) Close() error {
 	return nil
 }
 
+// Versions is part of source.Lister, the versions are listed once by Open.
+func (g *Github) Versions() ([]uint, error) {
+	return g.migrations.Versions(), nil
+}
+
 func (g *Github) First() (version uint, err error) {
 	g.ensureFields()
 
@@ -142,8 +142,8 @@ func (g *Gitlab) readDirectory() error {
 			continue
 		}
 
-		if !g.migrations.Append(m) {
-			return fmt.Errorf("unable to parse file %v", nodes[i].Name)
+		if err := g.migrations.AppendUnique(m, nil); err != nil {
+			return err
 		}
 	}
 
@@ -171,6 +171,11 @@ func (g *Gitlab) Close() error {
 	return nil
 }
 
+// Versions is part of source.Lister, the versions are listed once by Open.
+func (g *Gitlab) Versions() ([]uint, error) {
+	return g.migrations.Versions(), nil
+}
+
 func (g *Gitlab) First() (version uint, er error) {
 	if v, ok := g.migrations.First(); !ok {
 		return 0, &os.PathError{Op: "first", Path: g.path, Err: os.ErrNotExist}
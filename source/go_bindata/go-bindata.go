@@ -60,8 +60,8 @@ func WithInstance(instance interface{}) (source.Driver, error) {
 			continue // ignore files that we can't parse
 		}
 
-		if !bn.migrations.Append(m) {
-			return nil, fmt.Errorf("unable to parse file %v", fi)
+		if err := bn.migrations.AppendUnique(m, nil); err != nil {
+			return nil, err
 		}
 	}
 
@@ -0,0 +1,203 @@
+// Package gomigrate provides migrations written in Go, e.g. for data
+// transformations not expressible in SQL. They are run in between the SQL
+// migrations of another source driver by version.
+package gomigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// MigrateFunc applies a migration written in Go to db. It runs outside of
+// any transaction of the database driver; a failing function leaves the
+// database dirty like a failing SQL migration.
+type MigrateFunc func(ctx context.Context, db *sql.DB) error
+
+var (
+	ErrNilDB   = fmt.Errorf("no database")
+	ErrNilFunc = fmt.Errorf("no up or down function")
+)
+
+// ErrDuplicateVersion is returned by Register if version was registered before.
+type ErrDuplicateVersion struct {
+	Version uint
+}
+
+// Error implements the error interface.
+func (e ErrDuplicateVersion) Error() string {
+	return fmt.Sprintf("go migration %v registered twice", e.Version)
+}
+
+type goMigration struct {
+	identifier string
+	up, down   MigrateFunc
+}
+
+// GoMigrate is a source driver combining the registered Go migrations with
+// the migrations of another source driver. A Go migration takes precedence
+// over the migration of the other source in the same version and direction.
+type GoMigrate struct {
+	db         *sql.DB
+	sqlSource  source.Driver
+	sqlVersion map[uint]bool
+	migrations map[uint]*goMigration
+	// versions are the versions of both sources in ascending order
+	versions []uint
+}
+
+// WithInstance returns a driver running the Go migrations added by Register
+// with db. The versions of sqlSource, which may be nil, are listed once.
+// Closing the driver closes sqlSource.
+func WithInstance(db *sql.DB, sqlSource source.Driver) (*GoMigrate, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+
+	g := &GoMigrate{
+		db:         db,
+		sqlSource:  sqlSource,
+		sqlVersion: make(map[uint]bool),
+		migrations: make(map[uint]*goMigration),
+	}
+	if sqlSource != nil {
+		versions, err := listVersions(sqlSource)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			g.sqlVersion[v] = true
+		}
+		g.versions = versions
+	}
+	return g, nil
+}
+
+// listVersions returns the versions of d in ascending order.
+func listVersions(d source.Driver) ([]uint, error) {
+	if l, ok := d.(source.Lister); ok {
+		return l.Versions()
+	}
+
+	var versions []uint
+	v, err := d.First()
+	for err == nil {
+		versions = append(versions, v)
+		v, err = d.Next(v)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Register adds the Go migration of version. Either up or down may be nil
+// if the version has no such migration.
+func (g *GoMigrate) Register(version uint, identifier string, up, down MigrateFunc) error {
+	if up == nil && down == nil {
+		return ErrNilFunc
+	}
+	if _, ok := g.migrations[version]; ok {
+		return ErrDuplicateVersion{Version: version}
+	}
+	g.migrations[version] = &goMigration{identifier: identifier, up: up, down: down}
+
+	if !g.sqlVersion[version] {
+		i := sort.Search(len(g.versions), func(i int) bool { return g.versions[i] >= version })
+		g.versions = append(g.versions, 0)
+		copy(g.versions[i+1:], g.versions[i:])
+		g.versions[i] = version
+	}
+	return nil
+}
+
+// Open is part of source.Driver interface implementation.
+// Open cannot be called on the gomigrate driver, use WithInstance.
+func (g *GoMigrate) Open(url string) (source.Driver, error) {
+	return nil, errors.New("Open() cannot be called on the gomigrate driver")
+}
+
+func (g *GoMigrate) Close() error {
+	if g.sqlSource == nil {
+		return nil
+	}
+	return g.sqlSource.Close()
+}
+
+// Versions is part of source.Lister.
+func (g *GoMigrate) Versions() ([]uint, error) {
+	return append([]uint(nil), g.versions...), nil
+}
+
+func (g *GoMigrate) First() (version uint, err error) {
+	if len(g.versions) == 0 {
+		return 0, &os.PathError{Op: "first", Path: "gomigrate", Err: os.ErrNotExist}
+	}
+	return g.versions[0], nil
+}
+
+func (g *GoMigrate) Prev(version uint) (prevVersion uint, err error) {
+	if i, ok := g.index(version); ok && i > 0 {
+		return g.versions[i-1], nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("prev for version %v", version), Path: "gomigrate", Err: os.ErrNotExist}
+}
+
+func (g *GoMigrate) Next(version uint) (nextVersion uint, err error) {
+	if i, ok := g.index(version); ok && i < len(g.versions)-1 {
+		return g.versions[i+1], nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("next for version %v", version), Path: "gomigrate", Err: os.ErrNotExist}
+}
+
+// index returns the position of version in versions.
+func (g *GoMigrate) index(version uint) (int, bool) {
+	i := sort.Search(len(g.versions), func(i int) bool { return g.versions[i] >= version })
+	return i, i < len(g.versions) && g.versions[i] == version
+}
+
+func (g *GoMigrate) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
+	if m, ok := g.migrations[version]; ok && m.up != nil {
+		return &body{db: g.db, fn: m.up}, m.identifier, nil
+	}
+	if g.sqlVersion[version] {
+		return g.sqlSource.ReadUp(version)
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read up for version %v", version), Path: "gomigrate", Err: os.ErrNotExist}
+}
+
+func (g *GoMigrate) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
+	if m, ok := g.migrations[version]; ok && m.down != nil {
+		return &body{db: g.db, fn: m.down}, m.identifier, nil
+	}
+	if g.sqlVersion[version] {
+		return g.sqlSource.ReadDown(version)
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read down for version %v", version), Path: "gomigrate", Err: os.ErrNotExist}
+}
+
+// body is the empty body of a Go migration. Migrate runs it through
+// source.Executor instead of passing it to the database driver.
+type body struct {
+	db *sql.DB
+	fn MigrateFunc
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (b *body) Close() error {
+	return nil
+}
+
+// Execute is part of source.Executor.
+func (b *body) Execute(ctx context.Context) error {
+	return b.fn(ctx, b.db)
+}
@@ -0,0 +1,168 @@
+package gomigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/golang-migrate/migrate/v4/source/stub"
+	st "github.com/golang-migrate/migrate/v4/source/testing"
+	_ "modernc.org/sqlite"
+)
+
+func noop(ctx context.Context, db *sql.DB) error {
+	return nil
+}
+
+func Test(t *testing.T) {
+	sqlSource, err := (&stub.Stub{}).Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := source.NewMigrations()
+	m.Append(&source.Migration{Version: 1, Direction: source.Up})
+	m.Append(&source.Migration{Version: 1, Direction: source.Down})
+	m.Append(&source.Migration{Version: 4, Direction: source.Up})
+	m.Append(&source.Migration{Version: 5, Direction: source.Down})
+	sqlSource.(*stub.Stub).Migrations = m
+
+	d, err := WithInstance(&sql.DB{}, sqlSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Register(7, "7_go", noop, noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Register(3, "3_go", noop, nil); err != nil {
+		t.Fatal(err)
+	}
+	// the Go migration complements the SQL migration of version 4
+	if err := d.Register(4, "4_go", nil, noop); err != nil {
+		t.Fatal(err)
+	}
+
+	st.Test(t, d)
+}
+
+func TestRegister(t *testing.T) {
+	d, err := WithInstance(&sql.DB{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Register(1, "1_go", nil, nil); !errors.Is(err, ErrNilFunc) {
+		t.Errorf("expected ErrNilFunc, got %v", err)
+	}
+	if err := d.Register(1, "1_go", noop, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Register(1, "1_go", noop, nil); !errors.Is(err, ErrDuplicateVersion{Version: 1}) {
+		t.Errorf("expected ErrDuplicateVersion, got %v", err)
+	}
+
+	if _, err := WithInstance(nil, nil); !errors.Is(err, ErrNilDB) {
+		t.Errorf("expected ErrNilDB, got %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "sqlite.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	sqlSource, err := iofs.New(fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (name text);")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"3_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email text;")},
+		"3_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+	}, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := WithInstance(db, sqlSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the users are inserted between the two SQL migrations, i.e. before
+	// the email column exists
+	err = src.Register(2, "2_seed_users", func(ctx context.Context, db *sql.DB) error {
+		for _, name := range []string{"alice", "bob"} {
+			if _, err := db.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(ctx context.Context, db *sql.DB) error {
+		_, err := db.ExecContext(ctx, "DELETE FROM users")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbDrv, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithInstance("gomigrate", src, "sqlite", dbDrv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Steps(2); err != nil {
+		t.Fatal(err)
+	}
+	if v, dirty, err := m.Version(); err != nil || v != 2 || dirty {
+		t.Fatalf("expected version 2, got %v, %v, %v", v, dirty, err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE email IS NULL").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 users, got %v", count)
+	}
+
+	if err := m.Steps(-2); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected no users, got %v", count)
+	}
+
+	// a failing Go migration leaves the database dirty
+	failed := errors.New("failed")
+	if err := src.Register(4, "4_fail", func(ctx context.Context, db *sql.DB) error {
+		return failed
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	m, err = migrate.NewWithInstance("gomigrate", src, "sqlite", dbDrv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); !errors.Is(err, failed) {
+		t.Fatalf("expected the error of the Go migration, got %v", err)
+	}
+	if v, dirty, err := m.Version(); err != nil || v != 4 || !dirty {
+		t.Errorf("expected dirty version 4, got %v, %v, %v", v, dirty, err)
+	}
+}
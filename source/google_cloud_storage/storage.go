@@ -11,7 +11,9 @@ import (
 	"cloud.google.com/go/storage"
 	"context"
 	"github.com/golang-migrate/migrate/v4/source"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
 )
 
 func init() {
@@ -24,12 +26,21 @@ type gcs struct {
 	migrations *source.Migrations
 }
 
+// Open opens gcs://<bucket>/<prefix>. The client authenticates with
+// Application Default Credentials, which include the workload identity of a
+// GKE pod. With ?impersonate=<service account email> these credentials are
+// used to impersonate the service account.
 func (g *gcs) Open(folder string) (source.Driver, error) {
 	u, err := url.Parse(folder)
 	if err != nil {
 		return nil, err
 	}
-	client, err := storage.NewClient(context.Background())
+	ctx := context.Background()
+	opts, err := clientOptions(ctx, u.Query().Get("impersonate"))
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +56,23 @@ func (g *gcs) Open(folder string) (source.Driver, error) {
 	return &driver, nil
 }
 
+// clientOptions returns the options of the storage client impersonating
+// the target service account, if any. iamOpts configure the client of the
+// IAM Credentials API issuing the tokens of the service account.
+func clientOptions(ctx context.Context, target string, iamOpts ...option.ClientOption) ([]option.ClientOption, error) {
+	if target == "" {
+		return nil, nil
+	}
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: target,
+		Scopes:          []string{storage.ScopeReadOnly},
+	}, iamOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to impersonate %v: %w", target, err)
+	}
+	return []option.ClientOption{option.WithTokenSource(ts)}, nil
+}
+
 func (g *gcs) loadMigrations() error {
 	iter := g.bucket.Objects(context.Background(), &storage.Query{
 		Prefix:    g.prefix,
@@ -57,8 +85,8 @@ func (g *gcs) loadMigrations() error {
 		if parseErr != nil {
 			continue
 		}
-		if !g.migrations.Append(m) {
-			return fmt.Errorf("unable to parse file %v", object.Name)
+		if err := g.migrations.AppendUnique(m, nil); err != nil {
+			return err
 		}
 	}
 	if err != iterator.Done {
@@ -71,6 +99,11 @@ func (g *gcs) Close() error {
 	return nil
 }
 
+// Versions is part of source.Lister, the versions are listed once by Open.
+func (g *gcs) Versions() ([]uint, error) {
+	return g.migrations.Versions(), nil
+}
+
 func (g *gcs) First() (uint, error) {
 	v, ok := g.migrations.First()
 	if !ok {
@@ -1,11 +1,19 @@
 package googlecloudstorage
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
 	"testing"
 
+	"cloud.google.com/go/storage"
 	"github.com/fsouza/fake-gcs-server/fakestorage"
 	"github.com/golang-migrate/migrate/v4/source"
 	st "github.com/golang-migrate/migrate/v4/source/testing"
+	"google.golang.org/api/option"
 )
 
 func Test(t *testing.T) {
@@ -35,3 +43,94 @@ func Test(t *testing.T) {
 	}
 	st.Test(t, &driver)
 }
+
+// redirectTransport sends all requests to the server at host.
+type redirectTransport struct {
+	host string
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = t.host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestImpersonate(t *testing.T) {
+	var mu sync.Mutex
+	var iamPaths, authorizations []string
+
+	// fake IAM Credentials API issuing tokens of the impersonated service account
+	iam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		iamPaths = append(iamPaths, r.URL.Path)
+		mu.Unlock()
+		fmt.Fprint(w, `{"accessToken": "impersonated-token", "expireTime": "2099-01-01T00:00:00Z"}`)
+	}))
+	defer iam.Close()
+
+	// fake GCS JSON API listing a single migration
+	gcsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mu.Unlock()
+		if r.URL.Path != "/storage/v1/b/some-bucket/o" || r.URL.Query().Get("prefix") != "prod/migrations/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"kind": "storage#objects", "items": [{"bucket": "some-bucket", "name": "prod/migrations/1_foobar.up.sql"}]}`)
+	}))
+	defer gcsServer.Close()
+
+	ctx := context.Background()
+	iamURL, err := url.Parse(iam.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := clientOptions(ctx, "migrate@project.iam.gserviceaccount.com",
+		option.WithHTTPClient(&http.Client{Transport: redirectTransport{host: iamURL.Host}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := storage.NewClient(ctx, append(opts, option.WithEndpoint(gcsServer.URL+"/storage/v1/"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	driver := gcs{
+		bucket:     client.Bucket("some-bucket"),
+		prefix:     "prod/migrations/",
+		migrations: source.NewMigrations(),
+	}
+	if err := driver.loadMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := driver.First(); err != nil || v != 1 {
+		t.Fatalf("expected first version 1, got %v, %v", v, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expectedPath := "/v1/projects/-/serviceAccounts/migrate@project.iam.gserviceaccount.com:generateAccessToken"
+	if len(iamPaths) != 1 || iamPaths[0] != expectedPath {
+		t.Errorf("expected a token of the impersonated service account, got requests to %v", iamPaths)
+	}
+	if len(authorizations) == 0 {
+		t.Fatal("expected requests to GCS")
+	}
+	for _, authorization := range authorizations {
+		if authorization != "Bearer impersonated-token" {
+			t.Errorf("expected the impersonated token, got %q", authorization)
+		}
+	}
+}
+
+func TestClientOptionsWithoutImpersonation(t *testing.T) {
+	opts, err := clientOptions(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 0 {
+		t.Fatalf("expected plain Application Default Credentials, got %v", opts)
+	}
+}
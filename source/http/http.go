@@ -0,0 +1,279 @@
+// Package http reads migrations from a static HTTP(S) file server, listed
+// by a manifest.
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nethttp "net/http"
+	nurl "net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+func init() {
+	source.Register("http", &HTTP{})
+	source.Register("https", &HTTP{})
+}
+
+// DefaultManifest is the manifest path used if Config.Manifest is empty.
+const DefaultManifest = "index.txt"
+
+var (
+	ErrNoBaseURL = fmt.Errorf("no base url")
+	// ErrForeignHost is returned for files outside of the scheme and host of
+	// the base URL, which would receive the credentials of Config.
+	ErrForeignHost = fmt.Errorf("file not on the host of the base url")
+)
+
+type HTTP struct {
+	client     *nethttp.Client
+	config     *Config
+	baseURL    *nurl.URL
+	migrations *source.Migrations
+}
+
+type Config struct {
+	// BaseURL is the URL migration files and the manifest are relative to.
+	BaseURL string
+	// Manifest is the path of the manifest relative to BaseURL, defaults to
+	// DefaultManifest. The manifest is either a JSON array of file names or
+	// lists one file name per line, ignoring empty lines and lines starting
+	// with "#".
+	Manifest string
+
+	// Username and Password set basic auth for all requests.
+	Username string
+	Password string
+	// Token sets a bearer token for all requests. It takes precedence over
+	// basic auth.
+	Token string
+}
+
+// Open opens a http:// or https:// url. The query parameters manifest,
+// username, password and token set the Config and are not sent to the
+// server.
+func (h *HTTP) Open(url string) (source.Driver, error) {
+	u, err := nurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	config := &Config{
+		Manifest: q.Get("manifest"),
+		Username: q.Get("username"),
+		Password: q.Get("password"),
+		Token:    q.Get("token"),
+	}
+	for _, key := range []string{"manifest", "username", "password", "token"} {
+		q.Del(key)
+	}
+	u.RawQuery = q.Encode()
+	config.BaseURL = u.String()
+
+	return WithInstance(nethttp.DefaultClient, config)
+}
+
+// WithInstance returns a driver fetching the manifest and migration files of
+// config with client, which defaults to http.DefaultClient. The manifest is
+// fetched once; migration files are fetched when read.
+func WithInstance(client *nethttp.Client, config *Config) (source.Driver, error) {
+	if client == nil {
+		client = nethttp.DefaultClient
+	}
+	if config.BaseURL == "" {
+		return nil, ErrNoBaseURL
+	}
+
+	baseURL, err := nurl.Parse(config.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	// resolve relative references within the base path
+	if !strings.HasSuffix(baseURL.Path, "/") {
+		baseURL.Path += "/"
+	}
+
+	h := &HTTP{
+		client:     client,
+		config:     config,
+		baseURL:    baseURL,
+		migrations: source.NewMigrations(),
+	}
+
+	if err := h.loadManifest(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (h *HTTP) loadManifest() error {
+	manifest := h.config.Manifest
+	if manifest == "" {
+		manifest = DefaultManifest
+	}
+
+	body, err := h.get(manifest)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	names, err := parseManifest(data)
+	if err != nil {
+		return fmt.Errorf("unable to parse manifest %v: %w", manifest, err)
+	}
+
+	for _, name := range names {
+		m, err := source.DefaultParse(path.Base(name))
+		if err != nil {
+			continue // ignore files that we can't parse
+		}
+		if _, err := h.resolve(name); err != nil {
+			return fmt.Errorf("invalid manifest %v: %w", manifest, err)
+		}
+		// read the file from where the manifest lists it
+		m.Raw = name
+		if err := h.migrations.AppendUnique(m, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseManifest returns the file names listed by data, either as JSON array
+// or one per line.
+func parseManifest(data []byte) ([]string, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var names []string
+		if err := json.Unmarshal(data, &names); err != nil {
+			return nil, err
+		}
+		return names, nil
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, scanner.Err()
+}
+
+// resolve returns the URL of the file at name relative to the base URL.
+// Absolute URLs must have the scheme and host of the base URL, otherwise
+// ErrForeignHost is returned.
+func (h *HTTP) resolve(name string) (*nurl.URL, error) {
+	ref, err := nurl.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	u := h.baseURL.ResolveReference(ref)
+	if u.Scheme != h.baseURL.Scheme || u.Host != h.baseURL.Host {
+		return nil, fmt.Errorf("%v: %w", u.Redacted(), ErrForeignHost)
+	}
+	return u, nil
+}
+
+// get returns the body of the file at name relative to the base URL.
+func (h *HTTP) get(name string) (io.ReadCloser, error) {
+	u, err := h.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := nethttp.NewRequest(nethttp.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if h.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.config.Token)
+	} else if h.config.Username != "" || h.config.Password != "" {
+		req.SetBasicAuth(h.config.Username, h.config.Password)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == nethttp.StatusNotFound {
+		resp.Body.Close()
+		return nil, &os.PathError{Op: "get", Path: u.Redacted(), Err: os.ErrNotExist}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unable to get %v: %v", u.Redacted(), resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (h *HTTP) Close() error {
+	return nil
+}
+
+// Versions is part of source.Lister, the versions are listed once by Open.
+func (h *HTTP) Versions() ([]uint, error) {
+	return h.migrations.Versions(), nil
+}
+
+func (h *HTTP) First() (version uint, err error) {
+	if v, ok := h.migrations.First(); ok {
+		return v, nil
+	}
+	return 0, &os.PathError{Op: "first", Path: h.baseURL.Redacted(), Err: os.ErrNotExist}
+}
+
+func (h *HTTP) Prev(version uint) (prevVersion uint, err error) {
+	if v, ok := h.migrations.Prev(version); ok {
+		return v, nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("prev for version %v", version), Path: h.baseURL.Redacted(), Err: os.ErrNotExist}
+}
+
+func (h *HTTP) Next(version uint) (nextVersion uint, err error) {
+	if v, ok := h.migrations.Next(version); ok {
+		return v, nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("next for version %v", version), Path: h.baseURL.Redacted(), Err: os.ErrNotExist}
+}
+
+func (h *HTTP) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
+	if m, ok := h.migrations.Up(version); ok {
+		body, err := h.get(m.Raw)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, m.Identifier, nil
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read up for version %v", version), Path: h.baseURL.Redacted(), Err: os.ErrNotExist}
+}
+
+func (h *HTTP) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
+	if m, ok := h.migrations.Down(version); ok {
+		body, err := h.get(m.Raw)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, m.Identifier, nil
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read down for version %v", version), Path: h.baseURL.Redacted(), Err: os.ErrNotExist}
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"errors"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	st "github.com/golang-migrate/migrate/v4/source/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+var files = map[string]string{
+	"/migrations/1_foobar.up.sql":   "1 up",
+	"/migrations/1_foobar.down.sql": "1 down",
+	"/migrations/3_foobar.up.sql":   "3 up",
+	"/migrations/4_foobar.up.sql":   "4 up",
+	"/migrations/4_foobar.down.sql": "4 down",
+	"/migrations/5_foobar.down.sql": "5 down",
+	"/migrations/7_foobar.up.sql":   "7 up",
+	"/migrations/7_foobar.down.sql": "7 down",
+	"/migrations/index.txt": `# migrations
+1_foobar.up.sql
+1_foobar.down.sql
+3_foobar.up.sql
+4_foobar.up.sql
+4_foobar.down.sql
+5_foobar.down.sql
+7_foobar.up.sql
+7_foobar.down.sql
+
+README.md
+`,
+	"/migrations/index.json":          `["1_foobar.up.sql", "1_foobar.down.sql", "sub/2_foobar.up.sql"]`,
+	"/migrations/sub/2_foobar.up.sql": "2 up",
+}
+
+// newServer serves files, requiring the Authorization header auth if set.
+func newServer(t *testing.T, auth string) *httptest.Server {
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if auth != "" && r.Header.Get("Authorization") != auth {
+			w.WriteHeader(nethttp.StatusUnauthorized)
+			return
+		}
+		data, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(nethttp.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func Test(t *testing.T) {
+	ts := newServer(t, "")
+
+	h := &HTTP{}
+	d, err := h.Open(ts.URL + "/migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st.Test(t, d)
+}
+
+func TestJSONManifest(t *testing.T) {
+	ts := newServer(t, "")
+
+	h := &HTTP{}
+	d, err := h.Open(ts.URL + "/migrations/?manifest=index.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := d.Next(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint(2), version)
+
+	r, identifier, err := d.ReadUp(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "foobar", identifier)
+	assert.Equal(t, "2 up", string(body))
+}
+
+func TestAuth(t *testing.T) {
+	t.Run("basic", func(t *testing.T) {
+		ts := newServer(t, "Basic dXNlcjpzZWNyZXQ=")
+
+		h := &HTTP{}
+		if _, err := h.Open(ts.URL + "/migrations"); err == nil {
+			t.Fatal("expected error without credentials")
+		}
+		if _, err := h.Open(ts.URL + "/migrations?username=user&password=secret"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("bearer", func(t *testing.T) {
+		ts := newServer(t, "Bearer secret")
+
+		h := &HTTP{}
+		d, err := h.Open(ts.URL + "/migrations?token=secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, _, err := d.ReadUp(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+	})
+}
+
+func TestForeignHost(t *testing.T) {
+	var foreignAuth []string
+	foreign := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		foreignAuth = append(foreignAuth, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte("1 up"))
+	}))
+	t.Cleanup(foreign.Close)
+
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(nethttp.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/migrations/index.txt":
+			_, _ = w.Write([]byte(foreign.URL + "/1_foobar.up.sql\n"))
+		case "/migrations/scheme.txt":
+			_, _ = w.Write([]byte("ftp://" + r.Host + "/migrations/1_foobar.up.sql\n"))
+		case "/migrations/same.txt":
+			_, _ = w.Write([]byte("http://" + r.Host + "/other/1_foobar.up.sql\n"))
+		default:
+			w.WriteHeader(nethttp.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	h := &HTTP{}
+	for _, manifest := range []string{"index.txt", "scheme.txt"} {
+		if _, err := h.Open(ts.URL + "/migrations?token=secret&manifest=" + manifest); !errors.Is(err, ErrForeignHost) {
+			t.Errorf("expected ErrForeignHost for %v, got %v", manifest, err)
+		}
+	}
+	if len(foreignAuth) != 0 {
+		t.Errorf("expected no request to the foreign host, got %v", len(foreignAuth))
+	}
+
+	// absolute URLs on the host of the base URL are fine
+	if _, err := h.Open(ts.URL + "/migrations?token=secret&manifest=same.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMissingFile(t *testing.T) {
+	ts := newServer(t, "")
+
+	h := &HTTP{}
+	if _, err := h.Open(ts.URL + "/migrations?manifest=missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected error wrapping os.ErrNotExist, got %v", err)
+	}
+
+	if _, err := WithInstance(nil, &Config{}); !errors.Is(err, ErrNoBaseURL) {
+		t.Fatalf("expected ErrNoBaseURL, got %v", err)
+	}
+}
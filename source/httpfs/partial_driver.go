@@ -51,11 +51,8 @@ func (p *PartialDriver) Init(fs http.FileSystem, path string) error {
 			continue // ignore files that we can't parse
 		}
 
-		if !ms.Append(m) {
-			return source.ErrDuplicateMigration{
-				Migration: *m,
-				FileInfo:  file,
-			}
+		if err := ms.AppendUnique(m, file); err != nil {
+			return err
 		}
 	}
 
@@ -10,6 +10,7 @@ import (
 	"io/fs"
 	"path"
 	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4/source"
 )
@@ -19,6 +20,10 @@ type driver struct {
 }
 
 // New returns a new Driver from io/fs#FS and a relative path.
+// A leading "./" and trailing "/" of path are ignored, so that the path of
+// a go:embed pattern like "db/migrations/*.sql" can be passed as
+// "db/migrations" or "./db/migrations/". If path doesn't exist, an error
+// wrapping fs.ErrNotExist is returned.
 func New(fsys fs.FS, path string) (source.Driver, error) {
 	var i driver
 	if err := i.Init(fsys, path); err != nil {
@@ -27,6 +32,37 @@ func New(fsys fs.FS, path string) (source.Driver, error) {
 	return &i, nil
 }
 
+// NewSub returns a new Driver reading migrations from the sub tree of fsys
+// rooted at dir, see fs.Sub. Identifiers and errors are then relative to dir.
+func NewSub(fsys fs.FS, dir string) (source.Driver, error) {
+	if fsys == nil {
+		return nil, fmt.Errorf("failed to init driver with path %s: %w", dir, errNilFS)
+	}
+	dir = cleanPath(dir)
+	if _, err := fs.Stat(fsys, dir); err != nil {
+		return nil, fmt.Errorf("failed to init driver with path %s: %w", dir, err)
+	}
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init driver with path %s: %w", dir, err)
+	}
+	return New(sub, ".")
+}
+
+var errNilFS = errors.New("nil file system")
+
+// cleanPath turns p into a path valid for fs.FS, without leading "./" or a
+// trailing "/".
+func cleanPath(p string) string {
+	return path.Clean(strings.TrimPrefix(p, "/"))
+}
+
+// baseName returns the file name of e, as some file systems name entries
+// including their directory.
+func baseName(e fs.DirEntry) string {
+	return path.Base(e.Name())
+}
+
 // Open is part of source.Driver interface implementation.
 // Open cannot be called on the iofs passthrough driver.
 func (d *driver) Open(url string) (source.Driver, error) {
@@ -46,38 +82,108 @@ type PartialDriver struct {
 }
 
 // Init prepares not initialized IoFS instance to read migrations from a
-// io/fs#FS instance and a relative path.
+// io/fs#FS instance and a relative path. Subdirectories of path are
+// ignored, see InitRecursive.
 func (d *PartialDriver) Init(fsys fs.FS, path string) error {
-	entries, err := fs.ReadDir(fsys, path)
+	return d.init(fsys, path, false)
+}
+
+// InitRecursive is Init, also reading the migrations of all subdirectories
+// of path, e.g. one directory per subsystem. The versions of all
+// directories are ordered together, a version found in two directories
+// is reported as source.ErrDuplicateMigration.
+func (d *PartialDriver) InitRecursive(fsys fs.FS, path string) error {
+	return d.init(fsys, path, true)
+}
+
+func (d *PartialDriver) init(fsys fs.FS, path string, recursive bool) error {
+	if fsys == nil {
+		return errNilFS
+	}
+	path = cleanPath(path)
+
+	var ms *source.Migrations
+	var err error
+	if recursive {
+		ms, err = readTree(fsys, path)
+	} else {
+		ms, err = readDir(fsys, path)
+	}
 	if err != nil {
 		return err
 	}
 
+	d.fsys = fsys
+	d.path = path
+	d.migrations = ms
+	return nil
+}
+
+// readDir returns the migrations of the directory dir.
+func readDir(fsys fs.FS, dir string) (*source.Migrations, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
 	ms := source.NewMigrations()
 	for _, e := range entries {
 		if e.IsDir() {
 			continue
 		}
-		m, err := source.DefaultParse(e.Name())
+		m, err := source.DefaultParse(baseName(e))
 		if err != nil {
 			continue
 		}
 		file, err := e.Info()
 		if err != nil {
+			return nil, err
+		}
+		if err := ms.AppendUnique(m, file); err != nil {
+			return nil, err
+		}
+	}
+	return ms, nil
+}
+
+// readTree returns the migrations of the directory dir and its
+// subdirectories, whose Raw names are relative to dir.
+func readTree(fsys fs.FS, dir string) (*source.Migrations, error) {
+	ms := source.NewMigrations()
+	// dirs holds the directory of every version
+	dirs := make(map[uint]string)
+	err := fs.WalkDir(fsys, dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
 			return err
 		}
-		if !ms.Append(m) {
-			return source.ErrDuplicateMigration{
+		m, err := source.DefaultParse(baseName(e))
+		if err != nil {
+			return nil
+		}
+		rel := p
+		if dir != "." {
+			rel = strings.TrimPrefix(p, dir+"/")
+		}
+		m.Raw = rel
+
+		file, err := e.Info()
+		if err != nil {
+			return err
+		}
+		parent := path.Dir(rel)
+		if first, ok := dirs[m.Version]; ok && first != parent {
+			return fmt.Errorf("version %d in both %s and %s: %w", m.Version, first, parent, source.ErrDuplicateMigration{
 				Migration: *m,
 				FileInfo:  file,
-			}
+			})
 		}
+		dirs[m.Version] = parent
+		return ms.AppendUnique(m, file)
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	d.fsys = fsys
-	d.path = path
-	d.migrations = ms
-	return nil
+	return ms, nil
 }
 
 // Close is part of source.Driver interface implementation.
@@ -158,6 +264,43 @@ func (d *PartialDriver) ReadDown(version uint) (r io.ReadCloser, identifier stri
 	}
 }
 
+// Versions is part of source.Lister interface implementation.
+func (d *PartialDriver) Versions() ([]uint, error) {
+	return d.migrations.Versions(), nil
+}
+
+// Stat is part of source.Stater interface implementation.
+func (d *PartialDriver) Stat(version uint) (info source.Info, err error) {
+	up, hasUp := d.migrations.Up(version)
+	down, hasDown := d.migrations.Down(version)
+	if !hasUp && !hasDown {
+		return info, &fs.PathError{
+			Op:   "stat for version " + strconv.FormatUint(uint64(version), 10),
+			Path: d.path,
+			Err:  fs.ErrNotExist,
+		}
+	}
+	if hasUp {
+		if info.Up, err = d.stat(up); err != nil {
+			return source.Info{}, err
+		}
+	}
+	if hasDown {
+		if info.Down, err = d.stat(down); err != nil {
+			return source.Info{}, err
+		}
+	}
+	return info, nil
+}
+
+func (d *PartialDriver) stat(m *source.Migration) (*source.FileInfo, error) {
+	fi, err := fs.Stat(d.fsys, path.Join(d.path, m.Raw))
+	if err != nil {
+		return nil, err
+	}
+	return &source.FileInfo{Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
 func (d *PartialDriver) open(path string) (fs.File, error) {
 	f, err := d.fsys.Open(path)
 	if err == nil {
@@ -4,8 +4,12 @@
 package iofs_test
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"testing"
 
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	st "github.com/golang-migrate/migrate/v4/source/testing"
 )
@@ -19,3 +23,51 @@ func Test(t *testing.T) {
 
 	st.Test(t, d)
 }
+
+func TestNewPathPrefix(t *testing.T) {
+	for _, p := range []string{"./testdata/migrations", "testdata/migrations/", "./testdata/migrations/"} {
+		t.Run(p, func(t *testing.T) {
+			d, err := iofs.New(fs, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			st.Test(t, d)
+		})
+	}
+}
+
+func TestNewSub(t *testing.T) {
+	d, err := iofs.NewSub(fs, "testdata/migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st.Test(t, d)
+}
+
+func TestNewNotExist(t *testing.T) {
+	if _, err := iofs.New(fs, "testdata/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected error wrapping os.ErrNotExist, got %v", err)
+	}
+	if _, err := iofs.NewSub(fs, "testdata/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected error wrapping os.ErrNotExist, got %v", err)
+	}
+	if _, err := iofs.New(nil, "testdata/migrations"); err == nil {
+		t.Fatal("expected error for nil file system")
+	}
+}
+
+func TestVersions(t *testing.T) {
+	d, err := iofs.New(fs, "testdata/migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := d.(source.Lister).Versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(versions) != "[1 3 4 5 7]" {
+		t.Fatalf("expected versions [1 3 4 5 7], got %v", versions)
+	}
+}
@@ -1,6 +1,8 @@
 package source
 
 import (
+	"fmt"
+	"os"
 	"sort"
 )
 
@@ -65,6 +67,21 @@ func (i *Migrations) Append(m *Migration) (ok bool) {
 	return true
 }
 
+// AppendUnique is Append, returning an ErrDuplicateMigration naming both
+// files if a migration of the same version and direction was appended
+// before. file describes the file of m and may be nil.
+func (i *Migrations) AppendUnique(m *Migration, file os.FileInfo) error {
+	if m != nil {
+		if conflict, dup := i.migrations[m.Version][m.Direction]; dup {
+			return ErrDuplicateMigration{Migration: *m, FileInfo: file, Conflict: conflict}
+		}
+	}
+	if !i.Append(m) {
+		return fmt.Errorf("unable to append migration %v", m)
+	}
+	return nil
+}
+
 func (i *Migrations) buildIndex() {
 	i.index = make(uintSlice, 0, len(i.migrations))
 	for version := range i.migrations {
@@ -75,6 +92,11 @@ func (i *Migrations) buildIndex() {
 	})
 }
 
+// Versions returns all versions in ascending order, see Lister.
+func (i *Migrations) Versions() []uint {
+	return append([]uint(nil), i.index...)
+}
+
 func (i *Migrations) First() (version uint, ok bool) {
 	if len(i.index) == 0 {
 		return 0, false
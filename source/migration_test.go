@@ -1,6 +1,8 @@
 package source
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -12,6 +14,69 @@ func TestAppend(t *testing.T) {
 	// TODO
 }
 
+func TestAppendUnique(t *testing.T) {
+	ms := NewMigrations()
+	if err := ms.AppendUnique(&Migration{Version: 2, Direction: Up, Raw: "0002_a.up.sql"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ms.AppendUnique(&Migration{Version: 2, Direction: Down, Raw: "0002_a.down.sql"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ms.AppendUnique(&Migration{Version: 2, Direction: Up, Raw: "0002_b.up.sql"}, nil)
+	var dup ErrDuplicateMigration
+	if !errors.As(err, &dup) {
+		t.Fatalf("expected ErrDuplicateMigration, got %v", err)
+	}
+	if dup.Conflict == nil || dup.Conflict.Raw != "0002_a.up.sql" {
+		t.Errorf("expected conflict with 0002_a.up.sql, got %v", dup.Conflict)
+	}
+	expected := "duplicate migration files for version 2 up: 0002_a.up.sql and 0002_b.up.sql"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	// the duplicate is not appended
+	if m, ok := ms.Up(2); !ok || m.Raw != "0002_a.up.sql" {
+		t.Errorf("expected 0002_a.up.sql for version 2, got %v", m)
+	}
+}
+
+func TestMixedWidthVersions(t *testing.T) {
+	ms := NewMigrations()
+	// the versions of the names are compared as numbers, not as strings
+	for _, name := range []string{"20_d.up.sql", "0100_e.up.sql", "1_a.up.sql", "010_c.up.sql", "02_b.up.sql"} {
+		m, err := Parse(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ms.AppendUnique(m, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []uint{1, 2, 10, 20, 100}
+	var versions []uint
+	v, ok := ms.First()
+	for ok {
+		versions = append(versions, v)
+		v, ok = ms.Next(v)
+	}
+	if !reflect.DeepEqual(expected, versions) {
+		t.Fatalf("expected %v walking up, got %v", expected, versions)
+	}
+
+	versions = versions[:0]
+	v, ok = 100, true
+	for ok {
+		versions = append([]uint{v}, versions...)
+		v, ok = ms.Prev(v)
+	}
+	if !reflect.DeepEqual(expected, versions) {
+		t.Fatalf("expected %v walking down, got %v", expected, versions)
+	}
+}
+
 func TestBuildIndex(t *testing.T) {
 	// TODO
 }